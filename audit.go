@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// auditWriteConcern 审计条目使用的WriteConcern
+var auditWriteConcern = writeconcern.New(writeconcern.WMajority(), writeconcern.J(true))
+
+// SetAuditKey 设置审计标记字段，该字段存在且不为false的条目不参与采样与去重，
+// 默认Exec以 majority 与 journaled 的WriteConcern写入
+func SetAuditKey(key string) Option {
+	return func(o *options) {
+		o.auditKey = key
+	}
+}
+
+// isAudit 判断条目是否为审计条目
+func isAudit(o *options, entry *logrus.Entry) bool {
+	if o.auditKey == "" {
+		return false
+	}
+	v, ok := entry.Data[o.auditKey]
+	if !ok || v == nil {
+		return false
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return true
+}
+
+// writeConcern 返回条目写入使用的WriteConcern，依次为审计、级别策略与全局设置的WriteConcern，
+// 客户端未提供驱动层的数据库时审计条目使用设置的WriteConcern
+func (e *defaultExec) writeConcern(entry *logrus.Entry) *writeconcern.WriteConcern {
+	if isAudit(e.options(), entry) {
+		if _, err := e.database(); err == nil {
+			return auditWriteConcern
+		}
+	}
+	if wc, ok := e.levelWC[entry.Level]; ok {
+		return wc
+	}
+	return e.wc
+}
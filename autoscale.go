@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pm-esd/queue"
+)
+
+const (
+	// scaleInterval 检查队列压力的间隔
+	scaleInterval = 100 * time.Millisecond
+	// scaleUpTicks 队列持续积压多少次检查后增加工作线程
+	scaleUpTicks = 3
+	// scaleDownTicks 队列持续为空多少次检查后减少工作线程
+	scaleDownTicks = 10
+)
+
+// SetAutoScaleWorkers 设置工作线程数随队列压力在min与max之间伸缩：
+// 队列持续积压超过四分之一容量时增加工作线程，持续为空时减少。设置了SetQueue时不生效
+func SetAutoScaleWorkers(min, max int) Option {
+	return func(o *options) {
+		o.fixed("SetAutoScaleWorkers")
+		o.minWorkers = min
+		o.maxScaleWorkers = max
+	}
+}
+
+// workerCounter 能够返回当前工作线程数的队列
+type workerCounter interface {
+	Workers() int
+}
+
+// scalingQueue 工作线程数可伸缩的任务队列
+type scalingQueue struct {
+	jobs      chan queue.Jober
+	quit      chan struct{}
+	done      chan struct{}
+	wg        sync.WaitGroup
+	min       int
+	max       int
+	threshold int
+	workers   int64
+	once      sync.Once
+}
+
+func newScalingQueue(capacity, min, max int) *scalingQueue {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	threshold := capacity / 4
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &scalingQueue{
+		jobs:      make(chan queue.Jober, capacity),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+		min:       min,
+		max:       max,
+		threshold: threshold,
+	}
+}
+
+func (q *scalingQueue) Run() {
+	for i := 0; i < q.min; i++ {
+		q.spawn()
+	}
+	go q.monitor()
+}
+
+func (q *scalingQueue) spawn() {
+	atomic.AddInt64(&q.workers, 1)
+	q.wg.Add(1)
+	go q.work()
+}
+
+func (q *scalingQueue) work() {
+	defer q.wg.Done()
+	defer atomic.AddInt64(&q.workers, -1)
+	for {
+		select {
+		case job, ok := <-q.jobs:
+			if !ok {
+				return
+			}
+			job.Job()
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// monitor 按队列压力伸缩工作线程，连续多次检查满足条件时才调整，避免频繁伸缩
+func (q *scalingQueue) monitor() {
+	ticker := time.NewTicker(scaleInterval)
+	defer ticker.Stop()
+
+	var busy, idle int
+	for {
+		select {
+		case <-ticker.C:
+		case <-q.done:
+			return
+		}
+
+		n := len(q.jobs)
+		switch {
+		case n >= q.threshold:
+			busy, idle = busy+1, 0
+		case n == 0:
+			busy, idle = 0, idle+1
+		default:
+			busy, idle = 0, 0
+		}
+
+		workers := q.Workers()
+		if busy >= scaleUpTicks && workers < q.max {
+			q.spawn()
+			busy = 0
+		}
+		if idle >= scaleDownTicks && workers > q.min {
+			select {
+			case q.quit <- struct{}{}:
+			default:
+			}
+			idle = 0
+		}
+	}
+}
+
+func (q *scalingQueue) Push(job queue.Jober) {
+	q.jobs <- job
+}
+
+// Terminate 停止伸缩并等待工作线程处理完队列中的任务
+func (q *scalingQueue) Terminate() {
+	q.once.Do(func() {
+		close(q.done)
+		close(q.jobs)
+		q.wg.Wait()
+	})
+}
+
+func (q *scalingQueue) Len() int {
+	return len(q.jobs)
+}
+
+// Workers 返回当前的工作线程数
+func (q *scalingQueue) Workers() int {
+	return int(atomic.LoadInt64(&q.workers))
+}
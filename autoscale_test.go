@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// waitWorkers 等待工作线程数变为n
+func waitWorkers(t *testing.T, h *Hook, n int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for h.Stats().Workers != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("workers = %d after %s, want %d", h.Stats().Workers, timeout, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAutoScaleWorkersUnderLoad(t *testing.T) {
+	exec := newGateExec()
+	h := New(SetExec(exec), SetMaxQueues(16), SetAutoScaleWorkers(1, 3))
+	for i := 0; i < 16; i++ {
+		h.Fire(testEntry(logrus.InfoLevel, "a"))
+	}
+	waitWorkers(t, h, 1, time.Second)
+
+	// 工作线程阻塞时队列持续积压，工作线程数增加到上限
+	waitWorkers(t, h, 3, 3*scaleUpTicks*scaleInterval)
+	for i := 0; i < 3; i++ {
+		<-exec.started
+	}
+	if n := h.Stats().ActiveWorkers; n != 3 {
+		t.Fatalf("active workers = %d, want 3", n)
+	}
+	time.Sleep(2 * scaleUpTicks * scaleInterval)
+	if n := h.Stats().Workers; n != 3 {
+		t.Fatalf("workers = %d, want at most 3", n)
+	}
+
+	// 积压写完后队列持续为空，工作线程数减少到下限
+	close(exec.gate)
+	h.Drain()
+	if n := len(exec.Entries()); n != 16 {
+		t.Fatalf("%d entries written, want 16", n)
+	}
+	waitWorkers(t, h, 1, 3*scaleDownTicks*scaleInterval)
+	h.Flush()
+}
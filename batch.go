@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetBatchSize 设置批量写入的条目数量(小于等于1时不启用批量写入)
+func SetBatchSize(batchSize int) Option {
+	return func(o *options) {
+		o.fixed("SetBatchSize")
+		o.batchSize = batchSize
+	}
+}
+
+// SetFlushInterval 设置批量写入的刷新间隔
+func SetFlushInterval(flushInterval time.Duration) Option {
+	return func(o *options) {
+		o.fixed("SetFlushInterval")
+		o.flushInterval = flushInterval
+	}
+}
+
+// batcher 缓存条目，达到数量或间隔到期时批量写入
+type batcher struct {
+	lock    sync.Mutex
+	size    int
+	entries []*logrus.Entry
+	handle  func(context.Context, []*logrus.Entry)
+	ctx     func() context.Context
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newBatcher 创建批量写入器，handle 使用调用方传入的上下文写入，定时刷新与关闭时使用ctx返回的上下文
+func newBatcher(size int, interval time.Duration, handle func(context.Context, []*logrus.Entry), ctx func() context.Context) *batcher {
+	b := &batcher{
+		size:    size,
+		entries: make([]*logrus.Entry, 0, size),
+		handle:  handle,
+		ctx:     ctx,
+		done:    make(chan struct{}),
+	}
+	if interval > 0 {
+		b.wg.Add(1)
+		go b.loop(interval)
+	}
+	return b
+}
+
+func (b *batcher) loop(interval time.Duration) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush(b.ctx())
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// take 取出当前缓存的全部条目
+func (b *batcher) take() []*logrus.Entry {
+	entries := b.entries
+	b.entries = make([]*logrus.Entry, 0, b.size)
+	return entries
+}
+
+func (b *batcher) add(ctx context.Context, entry *logrus.Entry) {
+	b.lock.Lock()
+	b.entries = append(b.entries, entry)
+	if len(b.entries) < b.size {
+		b.lock.Unlock()
+		return
+	}
+	entries := b.take()
+	b.lock.Unlock()
+	b.handle(ctx, entries)
+}
+
+func (b *batcher) flush(ctx context.Context) {
+	b.lock.Lock()
+	entries := b.take()
+	b.lock.Unlock()
+	if len(entries) > 0 {
+		b.handle(ctx, entries)
+	}
+}
+
+// close 停止定时刷新并写入剩余条目
+func (b *batcher) close() {
+	close(b.done)
+	b.wg.Wait()
+	b.flush(b.ctx())
+}
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BatchError 批量写入部分失败，Indices为写入失败的条目在批次中的下标(升序)，
+// 钩子只重试这些条目并将其写入死信，其余条目视为写入成功
+type BatchError struct {
+	Indices []int
+	Err     error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d entries of the batch failed: %s", len(e.Indices), e.Err.Error())
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// partialError 将批量写入的错误转换为BatchError，ordered为true时首个失败之后的文档均未写入。
+// 无法确定失败的文档时返回原错误，整批视为失败
+func partialError(err error, n int, ordered bool) error {
+	var bwe mongo.BulkWriteException
+	if err == nil || !errors.As(err, &bwe) || bwe.WriteConcernError != nil || len(bwe.WriteErrors) == 0 {
+		return err
+	}
+
+	failed := make([]bool, n)
+	first := n
+	for _, we := range bwe.WriteErrors {
+		if we.Index < 0 || we.Index >= n {
+			return err
+		}
+		failed[we.Index] = true
+		if we.Index < first {
+			first = we.Index
+		}
+	}
+	if ordered {
+		for i := first; i < n; i++ {
+			failed[i] = true
+		}
+	}
+
+	var indices []int
+	for i, ok := range failed {
+		if ok {
+			indices = append(indices, i)
+		}
+	}
+	return &BatchError{Indices: indices, Err: err}
+}
+
+func containsIndex(indices []int, i int) bool {
+	for _, index := range indices {
+		if index == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断器已打开，写入被快速拒绝
+var ErrCircuitOpen = errors.New("mongo hook circuit breaker is open")
+
+// errExecPanic 写入引发了panic，熔断器将其计为失败
+var errExecPanic = errors.New("mongo hook exec panicked")
+
+// BreakerState 熔断器状态
+type BreakerState int32
+
+const (
+	// BreakerClosed 正常写入
+	BreakerClosed BreakerState = iota
+	// BreakerOpen 连续失败后拒绝写入，直到冷却时间结束
+	BreakerOpen
+	// BreakerHalfOpen 冷却结束后允许一次试探写入
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// SetCircuitBreaker 设置熔断器，连续failures次写入失败后在cooldown内拒绝写入，
+// 被拒绝的条目直接进入错误输出与死信，冷却结束后试探写入成功则恢复。
+// 失败次数只按连续计算，不限定时间窗口，任意一次写入成功即清零；引发panic的写入计为失败
+func SetCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(o *options) {
+		o.fixed("SetCircuitBreaker")
+		o.breakerFailures = failures
+		o.breakerCooldown = cooldown
+	}
+}
+
+type breaker struct {
+	lock        sync.Mutex
+	failures    int
+	cooldown    time.Duration
+	consecutive int
+	state       BreakerState
+	openedAt    time.Time
+}
+
+func newBreaker(failures int, cooldown time.Duration) *breaker {
+	return &breaker{failures: failures, cooldown: cooldown}
+}
+
+// allow 判断是否允许写入，冷却结束后只允许一次试探写入
+func (b *breaker) allow(now time.Time) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		return true
+	case BreakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// done 记录写入结果
+func (b *breaker) done(now time.Time, err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err == nil {
+		b.consecutive = 0
+		b.state = BreakerClosed
+		return
+	}
+	b.consecutive++
+	if b.state == BreakerHalfOpen || b.consecutive >= b.failures {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
+
+func (b *breaker) current() BreakerState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestBreakerRecoversFromPanickingProbe(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	var written []string
+	exec := funcExec(func(entry *logrus.Entry) error {
+		switch entry.Message {
+		case "fail":
+			return errors.New("write failed")
+		case "panic":
+			panic("probe panicked")
+		}
+		written = append(written, entry.Message)
+		return nil
+	})
+	h := New(SetExec(exec), SetMaxWorkers(1), SetCircuitBreaker(1, time.Second),
+		SetClock(clock.Now), SetOut(ioutil.Discard))
+	defer h.Close()
+
+	steps := []struct {
+		msg   string
+		state BreakerState
+	}{
+		{"fail", BreakerOpen},
+		{"rejected", BreakerOpen},
+		{"panic", BreakerOpen},
+		{"ok", BreakerClosed},
+	}
+	for i, step := range steps {
+		if i > 1 {
+			clock.Add(time.Second)
+		}
+		h.Fire(testEntry(logrus.InfoLevel, step.msg))
+		h.Drain()
+		if state := h.Stats().Breaker; state != step.state {
+			t.Fatalf("after %s: breaker = %s, want %s", step.msg, state, step.state)
+		}
+	}
+	if len(written) != 1 || written[0] != "ok" {
+		t.Fatalf("written = %q, want [ok]", written)
+	}
+}
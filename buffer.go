@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// OverflowPolicy 队列已满时的处理策略
+type OverflowPolicy int
+
+const (
+	// Block 阻塞等待队列出现空位(默认)
+	Block OverflowPolicy = iota
+	// DropNewest 丢弃新的条目并累加丢弃计数
+	DropNewest
+	// DropOldest 淘汰最早缓存的条目，为新的条目腾出空间
+	DropOldest
+)
+
+// SetOverflowPolicy 设置队列已满时的处理策略
+func SetOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = policy
+	}
+}
+
+// SetPriorityLevels 设置高优先级的级别，高优先级的条目先于其余条目写入。
+// 其余条目最多占用队列容量的四分之三，队列已满时高优先级的条目淘汰最早的低优先级条目
+func SetPriorityLevels(levels ...logrus.Level) Option {
+	return func(o *options) {
+		o.fixed("SetPriorityLevels")
+		o.priorityLevels = levels
+	}
+}
+
+const (
+	highLane = iota
+	lowLane
+)
+
+// ring 环形的条目队列
+type ring struct {
+	entries []*logrus.Entry
+	head    int
+	size    int
+}
+
+func (r *ring) push(entry *logrus.Entry) {
+	r.entries[(r.head+r.size)%len(r.entries)] = entry
+	r.size++
+}
+
+func (r *ring) pop() *logrus.Entry {
+	entry := r.entries[r.head]
+	r.entries[r.head] = nil
+	r.head = (r.head + 1) % len(r.entries)
+	r.size--
+	return entry
+}
+
+// remove 取出第i个条目，之后的条目依次前移
+func (r *ring) remove(i int) *logrus.Entry {
+	n := len(r.entries)
+	entry := r.entries[(r.head+i)%n]
+	for ; i < r.size-1; i++ {
+		r.entries[(r.head+i)%n] = r.entries[(r.head+i+1)%n]
+	}
+	r.entries[(r.head+r.size-1)%n] = nil
+	r.size--
+	return entry
+}
+
+// replace 用新的条目替换最早的条目，返回被替换的条目
+func (r *ring) replace(entry *logrus.Entry) *logrus.Entry {
+	old := r.entries[r.head]
+	r.entries[r.head] = entry
+	r.head = (r.head + 1) % len(r.entries)
+	return old
+}
+
+// buffer 有界的条目缓冲区，工作线程每次取出最早的高优先级条目，没有时取出最早的低优先级条目。
+// 未设置高优先级的级别时所有条目都是低优先级
+type buffer struct {
+	lock     sync.Mutex
+	notFull  *sync.Cond
+	lanes    [2]ring
+	size     int
+	capacity int
+	lowLimit int
+	priority []logrus.Level
+	clock    func() time.Time
+	blocked  time.Duration
+}
+
+func newBuffer(capacity int, priority []logrus.Level, clock func() time.Time) *buffer {
+	b := &buffer{
+		capacity: capacity,
+		lowLimit: capacity,
+		priority: priority,
+		clock:    clock,
+	}
+	b.lanes[lowLane].entries = make([]*logrus.Entry, capacity)
+	if len(priority) > 0 {
+		b.lanes[highLane].entries = make([]*logrus.Entry, capacity)
+		reserve := capacity / 4
+		if reserve == 0 && capacity > 1 {
+			reserve = 1
+		}
+		b.lowLimit = capacity - reserve
+	}
+	b.notFull = sync.NewCond(&b.lock)
+	return b
+}
+
+// lane 返回条目所在的队列
+func (b *buffer) lane(entry *logrus.Entry) int {
+	if containsLevel(b.priority, entry.Level) {
+		return highLane
+	}
+	return lowLane
+}
+
+// full 判断条目所在的队列是否已满
+func (b *buffer) full(lane int) bool {
+	return b.size == b.capacity || (lane == lowLane && b.lanes[lowLane].size >= b.lowLimit)
+}
+
+// put 按策略放入条目，stored 表示条目是否放入缓冲区，added 表示缓冲区条目数是否增加，
+// dropped 为被丢弃的条目
+func (b *buffer) put(entry *logrus.Entry, policy OverflowPolicy) (stored, added bool, dropped *logrus.Entry) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	lane := b.lane(entry)
+	r := &b.lanes[lane]
+	var start time.Time
+	defer func() {
+		if !start.IsZero() {
+			b.blocked += b.clock().Sub(start)
+		}
+	}()
+	for b.full(lane) {
+		if lane == highLane && b.lanes[lowLane].size > 0 {
+			dropped = b.lanes[lowLane].pop()
+			r.push(entry)
+			return true, false, dropped
+		}
+		switch policy {
+		case DropNewest:
+			return false, false, entry
+		case DropOldest:
+			if r.size == 0 {
+				return false, false, entry
+			}
+			return true, false, r.replace(entry)
+		default:
+			if start.IsZero() {
+				start = b.clock()
+			}
+			b.notFull.Wait()
+		}
+	}
+
+	r.push(entry)
+	b.size++
+	return true, true, nil
+}
+
+// pop 取出最早的条目，缓冲区为空时返回nil
+func (b *buffer) pop() *logrus.Entry {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.size == 0 {
+		return nil
+	}
+	r := &b.lanes[highLane]
+	if r.size == 0 {
+		r = &b.lanes[lowLane]
+	}
+	return b.taken(r.pop())
+}
+
+// popLevel 取出该级别最早的条目，没有该级别的条目时与pop相同
+func (b *buffer) popLevel(level logrus.Level) *logrus.Entry {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.size == 0 {
+		return nil
+	}
+	r := &b.lanes[b.lane(&logrus.Entry{Level: level})]
+	for i := 0; i < r.size; i++ {
+		if r.entries[(r.head+i)%len(r.entries)].Level == level {
+			return b.taken(r.remove(i))
+		}
+	}
+	if r = &b.lanes[highLane]; r.size == 0 {
+		r = &b.lanes[lowLane]
+	}
+	return b.taken(r.pop())
+}
+
+// taken 记录条目已被取出，唤醒等待空位的写入
+func (b *buffer) taken(entry *logrus.Entry) *logrus.Entry {
+	b.size--
+	b.notFull.Broadcast()
+	return entry
+}
+
+// blockedFor 返回阻塞策略下放入条目累计等待的时间
+func (b *buffer) blockedFor() time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.blocked
+}
+
+func (b *buffer) len() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.size
+}
@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pm-esd/queue"
+	"github.com/sirupsen/logrus"
+)
+
+func TestOverflowPolicyDrop(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   []string
+	}{
+		{DropNewest, []string{"0", "1", "2"}},
+		{DropOldest, []string{"0", "4", "5"}},
+	}
+	for _, tt := range tests {
+		exec := newGateExec()
+		h := New(SetExec(exec), SetMaxQueues(2), SetMaxWorkers(1), SetOverflowPolicy(tt.policy))
+		h.Fire(testEntry(logrus.InfoLevel, "0"))
+		<-exec.started
+
+		// 工作线程阻塞在第一个条目上，其余条目超出了队列容量
+		for i := 1; i < 6; i++ {
+			if err := h.Fire(testEntry(logrus.InfoLevel, strconv.Itoa(i))); err != nil {
+				t.Fatalf("policy %d: Fire returned %v", tt.policy, err)
+			}
+		}
+		if n := h.Stats().QueueLength; n != 2 {
+			t.Fatalf("policy %d: queue length = %d, want 2", tt.policy, n)
+		}
+		close(exec.gate)
+		h.Flush()
+
+		assertMessages(t, exec.Entries(), tt.want...)
+		if n := h.Stats().Dropped; n != 3 {
+			t.Fatalf("policy %d: dropped = %d, want 3", tt.policy, n)
+		}
+	}
+}
+
+func TestOverflowPolicyBlock(t *testing.T) {
+	exec := newGateExec()
+	h := New(SetExec(exec), SetMaxQueues(2), SetMaxWorkers(1))
+	h.Fire(testEntry(logrus.InfoLevel, "0"))
+	<-exec.started
+	h.Fire(testEntry(logrus.InfoLevel, "1"))
+	h.Fire(testEntry(logrus.InfoLevel, "2"))
+
+	done := make(chan struct{})
+	go func() {
+		h.Fire(testEntry(logrus.InfoLevel, "3"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Fire returned while the queue was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(exec.gate)
+	<-done
+	h.Flush()
+	assertMessages(t, exec.Entries(), "0", "1", "2", "3")
+	if stats := h.Stats(); stats.Dropped != 0 || stats.BlockedDuration <= 0 {
+		t.Fatalf("dropped = %d, blocked = %s", stats.Dropped, stats.BlockedDuration)
+	}
+}
+
+// levelQueue 在release前保存任务，之后按级别从高到低在一个协程中执行
+type levelQueue struct {
+	lock    sync.Mutex
+	jobs    []Job
+	release chan struct{}
+	done    chan struct{}
+}
+
+func newLevelQueue() *levelQueue {
+	return &levelQueue{release: make(chan struct{}), done: make(chan struct{})}
+}
+
+func (q *levelQueue) Run() {
+	go func() {
+		defer close(q.done)
+		<-q.release
+		q.lock.Lock()
+		jobs := q.jobs
+		q.lock.Unlock()
+		sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].Level() < jobs[j].Level() })
+		for _, job := range jobs {
+			job.Job()
+		}
+	}()
+}
+
+func (q *levelQueue) Push(job queue.Jober) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.jobs = append(q.jobs, job.(Job))
+}
+
+func (q *levelQueue) Terminate() { <-q.done }
+
+func (q *levelQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.jobs)
+}
+
+func TestQueueOrdersJobsByLevel(t *testing.T) {
+	exec := NewMemoryExec()
+	q := newLevelQueue()
+	h := New(SetExec(exec), SetQueue(q))
+	h.Fire(testEntry(logrus.InfoLevel, "info1"))
+	h.Fire(testEntry(logrus.ErrorLevel, "error1"))
+	h.Fire(testEntry(logrus.DebugLevel, "debug"))
+	h.Fire(testEntry(logrus.WarnLevel, "warn"))
+	h.Fire(testEntry(logrus.ErrorLevel, "error2"))
+	h.Fire(testEntry(logrus.InfoLevel, "info2"))
+	close(q.release)
+	h.Flush()
+
+	assertMessages(t, exec.Entries(), "error1", "error2", "warn", "info1", "info2", "debug")
+}
+
+func TestPriorityLevelsUnderLoad(t *testing.T) {
+	exec := newGateExec()
+	h := New(SetExec(exec), SetMaxQueues(8), SetMaxWorkers(1), SetOverflowPolicy(DropNewest),
+		SetPriorityLevels(logrus.ErrorLevel))
+	h.Fire(testEntry(logrus.InfoLevel, "blocked"))
+	<-exec.started
+
+	// 低优先级的条目最多占用6个位置，队列已满后错误条目淘汰最早的低优先级条目
+	for i := 0; i < 20; i++ {
+		h.Fire(testEntry(logrus.InfoLevel, "info"+strconv.Itoa(i)))
+	}
+	for i := 0; i < 3; i++ {
+		h.Fire(testEntry(logrus.ErrorLevel, "error"+strconv.Itoa(i)))
+	}
+	close(exec.gate)
+	h.Flush()
+
+	assertMessages(t, exec.Entries(), "blocked", "error0", "error1", "error2",
+		"info1", "info2", "info3", "info4", "info5")
+}
+
+func TestPriorityLevelsUnderConcurrentLoad(t *testing.T) {
+	exec := NewMemoryExec()
+	slow := funcExec(func(entry *logrus.Entry) error {
+		time.Sleep(100 * time.Microsecond)
+		return exec.Exec(entry)
+	})
+	const workers = 2
+	h := New(SetExec(slow), SetMaxQueues(16), SetMaxWorkers(workers), SetOverflowPolicy(DropNewest),
+		SetPriorityLevels(logrus.ErrorLevel))
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					h.Fire(testEntry(logrus.InfoLevel, "info"))
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+
+	// 错误条目最多等待取出与正在写入的条目，不排在已缓存的低优先级条目之后
+	for i := 0; i < 20; i++ {
+		msg := "error" + strconv.Itoa(i)
+		before := len(exec.Entries())
+		h.Fire(testEntry(logrus.ErrorLevel, msg))
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			entries := exec.Entries()
+			if j := indexOf(entries, msg); j >= 0 {
+				if j-before > 2*workers {
+					t.Fatalf("%s written after %d other entries", msg, j-before)
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s was not written", msg)
+			}
+			runtime.Gosched()
+		}
+	}
+	close(stop)
+	wg.Wait()
+	h.Flush()
+
+	if h.Stats().Dropped == 0 {
+		t.Fatal("no info entries were dropped under load")
+	}
+}
+
+// indexOf 返回消息为msg的条目的位置
+func indexOf(entries []*logrus.Entry, msg string) int {
+	for i, entry := range entries {
+		if entry.Message == msg {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const maximumCallerDepth = 25
+
+// callerFile 钩子注入的 file 字段，格式为"path:line"
+func callerFile(caller *runtime.Frame) string {
+	return fmt.Sprintf("%s:%d", caller.File, caller.Line)
+}
+
+var (
+	hookPackage   = reflect.TypeOf(Hook{}).PkgPath()
+	logrusPackage = reflect.TypeOf(logrus.Entry{}).PkgPath()
+)
+
+// SetCallerSkip 设置在第一个调用方帧之后额外跳过的帧数，用于封装了logger的辅助函数
+func SetCallerSkip(callerSkip int) Option {
+	return func(o *options) {
+		o.callerSkip = callerSkip
+	}
+}
+
+// SetCallerIgnore 设置解析调用方时忽略的函数，函数全名(如 "example.com/app/log.Infof")
+// 包含任一字符串时跳过该帧，与logrus及本包的帧一样不作为调用方，用于排除项目自己的日志封装函数。
+// 按子串匹配而不是正则表达式
+func SetCallerIgnore(patterns ...string) Option {
+	return func(o *options) {
+		o.callerIgnore = patterns
+	}
+}
+
+// ignoredFrame 判断函数是否匹配忽略的函数
+func ignoredFrame(function string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern != "" && strings.Contains(function, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// callerFrame 跳过logrus、本包与忽略的函数的帧后，再跳过skip个帧，返回第一个调用方帧
+func callerFrame(skip int, ignore []string) *runtime.Frame {
+	pcs := make([]uintptr, maximumCallerDepth)
+	depth := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:depth])
+
+	for f, again := frames.Next(); again; f, again = frames.Next() {
+		pkg := packageName(f.Function)
+		if pkg == hookPackage || pkg == logrusPackage || ignoredFrame(f.Function, ignore) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		return &f
+	}
+	return nil
+}
+
+// packageName 从完整的函数名中解析出包路径
+func packageName(f string) string {
+	for {
+		lastPeriod := strings.LastIndex(f, ".")
+		lastSlash := strings.LastIndex(f, "/")
+		if lastPeriod > lastSlash {
+			f = f[:lastPeriod]
+		} else {
+			break
+		}
+	}
+	return f
+}
+
+// SetResolveCaller 设置是否解析调用方并保存file与func字段(默认开启)，关闭后可减少每个条目的开销
+func SetResolveCaller(resolveCaller bool) Option {
+	return func(o *options) {
+		o.resolveCaller = resolveCaller
+	}
+}
+
+// SetStackTrace 设置需要记录调用栈的日志级别，调用栈保存在 stack 字段中
+func SetStackTrace(levels ...logrus.Level) Option {
+	return func(o *options) {
+		o.stackLevels = levels
+	}
+}
+
+// SetStackDepth 设置记录调用栈的最大帧数，负数无效：NewStrict返回ErrInvalidStackDepth，New输出警告并使用默认值
+func SetStackDepth(stackDepth int) Option {
+	return func(o *options) {
+		o.stackDepth = stackDepth
+	}
+}
+
+// stackTrace 跳过logrus与本包的帧，返回最多depth个 "file:line func" 格式的帧
+func stackTrace(depth int) []string {
+	pcs := make([]uintptr, maximumCallerDepth+depth)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	stack := make([]string, 0, depth)
+	for f, again := frames.Next(); again && len(stack) < depth; f, again = frames.Next() {
+		if len(stack) == 0 {
+			pkg := packageName(f.Function)
+			if pkg == hookPackage || pkg == logrusPackage {
+				continue
+			}
+		}
+		stack = append(stack, fmt.Sprintf("%s:%d %s", f.File, f.Line, f.Function))
+	}
+	return stack
+}
@@ -0,0 +1,114 @@
+package logger_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/pm-esd/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// logWrapped 封装了logger的辅助函数
+func logWrapped(log *logrus.Logger, msg string) {
+	log.Info(msg)
+}
+
+func newCallerLogger(opt ...logger.Option) (*logrus.Logger, *logger.MemoryExec) {
+	exec := logger.NewMemoryExec()
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	log.SetReportCaller(true)
+	log.AddHook(logger.New(append(opt, logger.SetExec(exec), logger.SetSync(true))...))
+	return log, exec
+}
+
+func TestCallerSkipWrapper(t *testing.T) {
+	tests := []struct {
+		skip int
+		fn   string
+	}{
+		{0, "github.com/pm-esd/logger_test.logWrapped"},
+		{1, "github.com/pm-esd/logger_test.TestCallerSkipWrapper"},
+	}
+	for _, tt := range tests {
+		log, exec := newCallerLogger(logger.SetCallerSkip(tt.skip))
+		logWrapped(log, "a")
+		_, file, line, _ := runtime.Caller(0)
+
+		data := exec.Entries()[0].Data
+		if data["func"] != tt.fn {
+			t.Fatalf("skip %d: func = %v, want %s", tt.skip, data["func"], tt.fn)
+		}
+		if tt.skip == 1 {
+			if want := fmt.Sprintf("%s:%d", file, line-1); data["file"] != want {
+				t.Fatalf("file = %v, want %s", data["file"], want)
+			}
+		} else if !strings.HasPrefix(data["file"].(string), file+":") {
+			t.Fatalf("file = %v, want %s", data["file"], file)
+		}
+	}
+}
+
+func TestCallerIgnoreWrapper(t *testing.T) {
+	log, exec := newCallerLogger(logger.SetCallerIgnore("logger_test.logWrapped"))
+	logWrapped(log, "a")
+
+	if fn := exec.Entries()[0].Data["func"]; fn != "github.com/pm-esd/logger_test.TestCallerIgnoreWrapper" {
+		t.Fatalf("func = %v", fn)
+	}
+}
+
+func TestInvalidStackDepth(t *testing.T) {
+	if _, err := logger.NewStrict(logger.SetExec(logger.NewMemoryExec()), logger.SetStackDepth(-1)); err != logger.ErrInvalidStackDepth {
+		t.Fatalf("NewStrict error = %v, want ErrInvalidStackDepth", err)
+	}
+
+	for _, depth := range []int{-1, -100} {
+		log, exec := newCallerLogger(logger.SetStackDepth(depth), logger.SetStackTrace(logrus.InfoLevel),
+			logger.SetOut(ioutil.Discard))
+		log.Info("a")
+		if stack, _ := exec.Entries()[0].Data["stack"].([]string); len(stack) == 0 {
+			t.Fatalf("depth %d: no stack recorded", depth)
+		}
+	}
+}
+
+func TestResolveCallerOff(t *testing.T) {
+	log, exec := newCallerLogger(logger.SetResolveCaller(false))
+	log.Info("a")
+	data := exec.Entries()[0].Data
+	if _, ok := data["file"]; ok {
+		t.Fatalf("file = %v, want no caller fields", data["file"])
+	}
+	if _, ok := data["func"]; ok {
+		t.Fatalf("func = %v, want no caller fields", data["func"])
+	}
+}
+
+// discardExec 丢弃写入的条目
+type discardExec struct{}
+
+func (discardExec) Exec(entry *logrus.Entry) error { return nil }
+func (discardExec) Ping(ctx context.Context) error { return nil }
+func (discardExec) Close() error                   { return nil }
+
+func BenchmarkResolveCaller(b *testing.B) {
+	for _, resolve := range []bool{true, false} {
+		b.Run(fmt.Sprintf("resolve=%v", resolve), func(b *testing.B) {
+			log := logrus.New()
+			log.SetOutput(ioutil.Discard)
+			log.SetReportCaller(true)
+			log.AddHook(logger.New(logger.SetExec(discardExec{}), logger.SetSync(true),
+				logger.SetCallerSkip(1), logger.SetResolveCaller(resolve)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logWrapped(log, "a")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cardinalityOther 超出不同值数量上限后字段使用的值
+const cardinalityOther = "other"
+
+// SetCardinalityLimit 限制字段在每个时间窗口内不同值的数量，达到max后新出现的值替换为 "other"，
+// 窗口结束时重新统计。可以为多个字段分别设置
+func SetCardinalityLimit(field string, max int, window time.Duration) Option {
+	return func(o *options) {
+		o.fixed("SetCardinalityLimit")
+		limits := make(map[string]cardinalityLimit, len(o.cardinality)+1)
+		for k, v := range o.cardinality {
+			limits[k] = v
+		}
+		limits[field] = cardinalityLimit{max: max, window: window}
+		o.cardinality = limits
+	}
+}
+
+type cardinalityLimit struct {
+	max    int
+	window time.Duration
+}
+
+// cardinality 统计字段在当前窗口内出现的不同值，最多保存max个
+type cardinality struct {
+	lock   sync.Mutex
+	limit  cardinalityLimit
+	start  time.Time
+	values map[string]struct{}
+}
+
+func newCardinalities(limits map[string]cardinalityLimit) map[string]*cardinality {
+	if len(limits) == 0 {
+		return nil
+	}
+	cs := make(map[string]*cardinality, len(limits))
+	for field, limit := range limits {
+		cs[field] = &cardinality{limit: limit, values: make(map[string]struct{})}
+	}
+	return cs
+}
+
+// allow 判断值是否在上限之内
+func (c *cardinality) allow(now time.Time, value string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.limit.window > 0 && now.Sub(c.start) >= c.limit.window {
+		c.start = now
+		c.values = make(map[string]struct{}, len(c.values))
+	}
+	if _, ok := c.values[value]; ok {
+		return true
+	}
+	if len(c.values) >= c.limit.max {
+		return false
+	}
+	c.values[value] = struct{}{}
+	return true
+}
+
+// limitCardinality 将复制后条目中超出上限的字段值替换为 "other"
+func (h *Hook) limitCardinality(entry *logrus.Entry) {
+	if len(h.cardinality) == 0 {
+		return
+	}
+	now := h.options().clock()
+	for field, c := range h.cardinality {
+		v, ok := entry.Data[field]
+		if !ok {
+			continue
+		}
+		if !c.allow(now, fmt.Sprint(v)) {
+			entry.Data[field] = cardinalityOther
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Clone 创建一个与h共用Exec的钩子，以h当前的参数为基础应用opt，用于多个logger写入同一集合但级别、过滤器等不同的场景。
+// 新钩子有独立的队列与工作线程，不共用h的SetQueue队列与溢出文件，Flush与Close互不影响；
+// opt中未设置SetDeadLetter时与h共用死信输出，两个钩子的死信逐条串行写入；
+// 关闭新钩子时不关闭共用的Exec，opt中的SetExec会被忽略。
+// 共用的默认Exec按h当前的集合路由、字段名称等参数写入
+func (h *Hook) Clone(opt ...Option) *Hook {
+	o := *h.options()
+	o.queue = nil
+	o.spillFile = ""
+	o.deadLetter, o.sharedDead = nil, nil
+	for _, fn := range opt {
+		fn(&o)
+	}
+	if o.deadLetter == nil && h.dead != nil {
+		o.deadLetter, o.sharedDead = h.options().deadLetter, h.dead
+	}
+	o.exec = nil
+	if exec := h.options().exec; exec != nil {
+		o.exec = sharedExec{exec}
+	}
+	return newHook(o)
+}
+
+// sharedExec 共用的Exec，Close不关闭原Exec
+type sharedExec struct {
+	exec ExecCloser
+}
+
+func (s sharedExec) cancellable(batch bool) bool {
+	return cancellable(s.exec, batch)
+}
+
+func (s sharedExec) Exec(entry *logrus.Entry) error {
+	return s.exec.Exec(entry)
+}
+
+func (s sharedExec) ExecContext(ctx context.Context, entry *logrus.Entry) error {
+	return execContext(ctx, s.exec, entry)
+}
+
+func (s sharedExec) BatchExec(entries []*logrus.Entry) error {
+	if be, ok := s.exec.(BatchExecer); ok {
+		return be.BatchExec(entries)
+	}
+	return s.each(context.Background(), entries)
+}
+
+func (s sharedExec) BatchExecContext(ctx context.Context, entries []*logrus.Entry) error {
+	if be, ok := s.exec.(BatchContextExecer); ok {
+		return be.BatchExecContext(ctx, entries)
+	}
+	if be, ok := s.exec.(BatchExecer); ok {
+		return be.BatchExec(entries)
+	}
+	return s.each(ctx, entries)
+}
+
+// each 原Exec未实现BatchExecer时逐条写入，部分失败时返回BatchError
+func (s sharedExec) each(ctx context.Context, entries []*logrus.Entry) error {
+	return eachEntry(entries, func(entry *logrus.Entry) error {
+		return execContext(ctx, s.exec, entry)
+	})
+}
+
+func (s sharedExec) Ping(ctx context.Context) error {
+	return s.exec.Ping(ctx)
+}
+
+func (s sharedExec) Close() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCloneSharesDeadLetter(t *testing.T) {
+	fail := funcExec(func(*logrus.Entry) error { return errors.New("write failed") })
+	dead := &lineWriter{}
+	h := New(SetExec(fail), SetDeadLetter(dead), SetErrorHandler(func(*logrus.Entry, error) {}))
+	c := h.Clone()
+	if c.dead != h.dead {
+		t.Fatal("clone did not share the dead letter")
+	}
+
+	var wg sync.WaitGroup
+	for _, hook := range []*Hook{h, c} {
+		hook := hook
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				hook.Fire(testEntry(logrus.InfoLevel, fmt.Sprint(i)))
+			}
+		}()
+	}
+	wg.Wait()
+	h.Flush()
+	c.Flush()
+
+	// 两个钩子的死信串行写入，每条记录完整
+	if atomic.LoadInt32(&dead.concurrent) != 0 {
+		t.Fatal("dead letter records were written concurrently")
+	}
+	if n := len(dead.writes); n != 100 {
+		t.Fatalf("dead letter records = %d, want 100", n)
+	}
+
+	var own bytes.Buffer
+	if c := h.Clone(SetDeadLetter(&own)); c.dead == h.dead || c.dead.w != &own {
+		t.Fatal("clone with its own dead letter shared the original")
+	}
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetFieldCoercions 设置默认文档中字段值的类型转换，转换函数返回error表示转换失败，
+// 转换失败的字段保留原值并设置 _coerce_error: true
+func SetFieldCoercions(coercions map[string]func(interface{}) interface{}) Option {
+	return func(o *options) {
+		o.coercions = coercions
+	}
+}
+
+// CoerceInt 将字符串转换为int64
+func CoerceInt(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	return n
+}
+
+// CoerceFloat 将字符串转换为float64
+func CoerceFloat(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	return f
+}
+
+// CoerceBool 将字符串转换为bool
+func CoerceBool(v interface{}) interface{} {
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	return b
+}
+
+// coerce 按设置转换文档中的字段值
+func coerce(item bson.M, coercions map[string]func(interface{}) interface{}) {
+	for field, fn := range coercions {
+		v, ok := item[field]
+		if !ok {
+			continue
+		}
+		res := fn(v)
+		if _, failed := res.(error); failed {
+			item["_coerce_error"] = true
+			continue
+		}
+		item[field] = res
+	}
+}
@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pm-esd/mongodb"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// CollectionTemplate 根据条目时间返回写入的集合名称，如按日期返回 logs_2006_01_02
+type CollectionTemplate func(t time.Time) string
+
+// SetCollectionTemplate 设置默认Exec按条目时间计算集合名称，用于按时间分区的集合。
+// 集合路由返回非空名称时优先使用路由；TTL与固定集合只作用于默认集合
+func SetCollectionTemplate(template CollectionTemplate) Option {
+	return func(o *options) {
+		o.collectionTemplate = template
+	}
+}
+
+// SetTenantField 设置默认Exec按条目的租户字段选择集合，写入 prefix+租户(如 logs_X)。
+// 字段不存在、为空或包含字母、数字、"_"、"-"、"."以外的字符时写入默认集合；
+// 集合路由返回非空名称时优先使用路由，租户集合优先于集合模板。
+// 解析后的集合按集合名称缓存，最多缓存maxCachedCollections个，超出时清空缓存后重新解析
+func SetTenantField(field, prefix string) Option {
+	return func(o *options) {
+		o.tenantField = field
+		o.tenantPrefix = prefix
+	}
+}
+
+// tenantCollection 返回条目租户对应的集合名称，没有有效的租户时返回空字符串
+func tenantCollection(o *options, entry *logrus.Entry) string {
+	if o.tenantField == "" {
+		return ""
+	}
+	tenant, ok := entry.Data[o.tenantField].(string)
+	if !ok || tenant == "" {
+		return ""
+	}
+	for _, c := range tenant {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-', c == '.':
+		default:
+			return ""
+		}
+	}
+	return o.tenantPrefix + tenant
+}
+
+// target 写入的集合名称与WriteConcern
+type target struct {
+	name string
+	wc   *writeconcern.WriteConcern
+}
+
+// target 返回条目写入的集合与WriteConcern
+func (e *defaultExec) target(entry *logrus.Entry) target {
+	return target{name: e.collectionName(entry), wc: e.writeConcern(entry)}
+}
+
+// maxCachedCollections 每种集合最多缓存的数量，租户或按时间分区的集合数量没有上限
+const maxCachedCollections = 1024
+
+// collectionCache 缓存已解析的驱动层集合与客户端集合
+type collectionCache struct {
+	lock    sync.RWMutex
+	colls   map[target]*mongo.Collection
+	clients map[string]*mongodb.Collection
+}
+
+// collectionName 返回条目写入的集合名称
+func (e *defaultExec) collectionName(entry *logrus.Entry) string {
+	o := e.options()
+	if name := e.policyCollection(entry.Level); name != "" {
+		return name
+	}
+	if router := o.router; router != nil {
+		if name := router(entry); name != "" {
+			return name
+		}
+	}
+	if name := tenantCollection(o, entry); name != "" {
+		return name
+	}
+	if template := o.collectionTemplate; template != nil {
+		t := entry.Time
+		if t.IsZero() {
+			t = o.clock()
+		}
+		if name := template(t); name != "" {
+			return name
+		}
+	}
+	return e.cName
+}
+
+// collection 返回写入的驱动层集合，未设置WriteConcern、未使用驱动层集合创建且上下文不能取消时返回nil，
+// 由客户端写入。客户端未提供驱动层的数据库时同样返回nil，此时写入无法被上下文中止
+func (e *defaultExec) collection(ctx context.Context, t target) (*mongo.Collection, error) {
+	if e.coll != nil || t.wc != nil {
+		return e.driverCollection(t)
+	}
+	if ctx.Done() == nil {
+		return nil, nil
+	}
+	coll, err := e.driverCollection(t)
+	if err == errNoDatabase {
+		return nil, nil
+	}
+	return coll, err
+}
+
+// driverCollection 返回驱动层集合，解析后的集合会被缓存
+func (e *defaultExec) driverCollection(t target) (*mongo.Collection, error) {
+	c := &e.colls
+	c.lock.RLock()
+	coll, ok := c.colls[t]
+	c.lock.RUnlock()
+	if ok {
+		return coll, nil
+	}
+
+	coll, err := e.resolveCollection(t)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	if c.colls == nil || len(c.colls) >= maxCachedCollections {
+		c.colls = make(map[target]*mongo.Collection)
+	}
+	c.colls[t] = coll
+	c.lock.Unlock()
+	return coll, nil
+}
+
+// clientCollection 返回客户端的集合，按集合名称缓存
+func (e *defaultExec) clientCollection(name string) *mongodb.Collection {
+	c := &e.colls
+	c.lock.RLock()
+	coll, ok := c.clients[name]
+	c.lock.RUnlock()
+	if ok {
+		return coll
+	}
+
+	coll = e.sess.Collection(name)
+	c.lock.Lock()
+	if c.clients == nil || len(c.clients) >= maxCachedCollections {
+		c.clients = make(map[string]*mongodb.Collection)
+	}
+	c.clients[name] = coll
+	c.lock.Unlock()
+	return coll
+}
+
+// resolveCollection 解析驱动层集合，设置了WriteConcern时使用该WriteConcern
+func (e *defaultExec) resolveCollection(t target) (*mongo.Collection, error) {
+	if e.coll != nil {
+		coll := e.coll
+		if t.name != e.cName {
+			coll = coll.Database().Collection(t.name)
+		}
+		if t.wc == nil {
+			return coll, nil
+		}
+		return coll.Clone(mopts.Collection().SetWriteConcern(t.wc))
+	}
+	db, err := e.database()
+	if err != nil {
+		return nil, err
+	}
+	if t.wc == nil {
+		return db.Collection(t.name), nil
+	}
+	return db.Collection(t.name, mopts.Collection().SetWriteConcern(t.wc)), nil
+}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"strconv"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCollectionCacheBounded(t *testing.T) {
+	client, err := mongo.NewClient(mopts.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &defaultExec{coll: client.Database("logs").Collection("logs"), cName: "logs"}
+
+	first, err := e.driverCollection(target{name: "logs_0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := e.driverCollection(target{name: "logs_0"}); again != first {
+		t.Fatal("resolved collection was not cached")
+	}
+	// 租户数量超过缓存上限时缓存不会无限增长
+	for i := 0; i < 3*maxCachedCollections; i++ {
+		coll, err := e.driverCollection(target{name: "logs_" + strconv.Itoa(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coll.Name() != "logs_"+strconv.Itoa(i) {
+			t.Fatalf("collection = %s", coll.Name())
+		}
+	}
+	if n := len(e.colls.colls); n > maxCachedCollections {
+		t.Fatalf("%d collections cached, want at most %d", n, maxCachedCollections)
+	}
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// encodingSuffix 标记字段编码的伴随字段后缀
+	encodingSuffix = "_encoding"
+	// encodingGzip gzip压缩编码
+	encodingGzip = "gzip"
+)
+
+// SetCompressThreshold 设置字段压缩阈值，默认文档中长度超过阈值的字符串字段以gzip压缩保存，
+// 并添加 <字段>_encoding: "gzip" 标记，可使用 DecodeField 还原
+func SetCompressThreshold(bytes int) Option {
+	return func(o *options) {
+		o.compressThreshold = bytes
+	}
+}
+
+// compress 压缩文档中超过阈值的字符串字段
+func compress(item bson.M, threshold int) error {
+	var keys []string
+	for k, v := range item {
+		if s, ok := v.(string); ok && len(s) > threshold {
+			keys = append(keys, k)
+		}
+	}
+
+	for _, k := range keys {
+		s := item[k].(string)
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write([]byte(s)); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		item[k] = buf.Bytes()
+		item[k+encodingSuffix] = encodingGzip
+	}
+	return nil
+}
+
+// DecodeField 还原被压缩的字段，encoding 为伴随字段 <字段>_encoding 的值，
+// value 可以是 []byte 或从数据库读取的 primitive.Binary
+func DecodeField(value interface{}, encoding string) (string, error) {
+	if encoding == "" {
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+	if encoding != encodingGzip {
+		return "", fmt.Errorf("unknown field encoding: %q", encoding)
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case primitive.Binary:
+		data = v.Data
+	default:
+		return "", fmt.Errorf("unexpected compressed field type: %T", value)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+	buf, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetDeadLetter 设置死信输出，重试耗尽后仍写入失败的条目序列化(默认为JSON)后逐行写入w，
+// 设置了SetDeserializer时按溢出文件的长度前缀格式写入
+func SetDeadLetter(w io.Writer) Option {
+	return func(o *options) {
+		o.fixed("SetDeadLetter")
+		o.deadLetter = w
+	}
+}
+
+// Serializer 将条目序列化后写入死信与溢出文件
+type Serializer func(*logrus.Entry) ([]byte, error)
+
+// SetSerializer 设置死信与溢出文件的序列化方式，默认为JSON。
+// 使用其他格式时需通过SetDeserializer设置对应的解析方式，否则溢出文件无法重放
+func SetSerializer(serializer Serializer) Option {
+	return func(o *options) {
+		o.fixed("SetSerializer")
+		o.serializer = serializer
+	}
+}
+
+// Deserializer 将Serializer序列化的数据还原为条目
+type Deserializer func([]byte) (*logrus.Entry, error)
+
+// SetDeserializer 设置溢出文件重放与ReplayFile解析条目的方式，默认为JSON。
+// 设置后死信同样使用长度前缀格式，二进制格式的序列化结果可能包含换行
+func SetDeserializer(deserializer Deserializer) Option {
+	return func(o *options) {
+		o.fixed("SetDeserializer")
+		o.deserializer = deserializer
+	}
+}
+
+// deserialize 返回解析条目的方式
+func (o *options) deserialize() Deserializer {
+	if o.deserializer == nil {
+		return unmarshalEntry
+	}
+	return o.deserializer
+}
+
+// deadLetterEntry 死信中条目的JSON结构
+type deadLetterEntry struct {
+	Level   string                 `json:"level"`
+	Time    time.Time              `json:"time"`
+	Message string                 `json:"message"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// marshalEntry 将条目序列化为JSON
+func marshalEntry(entry *logrus.Entry) ([]byte, error) {
+	item := deadLetterEntry{
+		Level:   entry.Level.String(),
+		Time:    entry.Time,
+		Message: entry.Message,
+		Data:    make(map[string]interface{}, len(entry.Data)),
+	}
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = errorMessage(err)
+		}
+		item.Data[k] = v
+	}
+	return json.Marshal(item)
+}
+
+// unmarshalEntry 将marshalEntry序列化的JSON还原为条目
+func unmarshalEntry(buf []byte) (*logrus.Entry, error) {
+	var item deadLetterEntry
+	if err := json.Unmarshal(buf, &item); err != nil {
+		return nil, err
+	}
+	level, err := logrus.ParseLevel(item.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := logrus.NewEntry(nil)
+	entry.Level = level
+	entry.Time = item.Time
+	entry.Message = item.Message
+	for k, v := range item.Data {
+		entry.Data[k] = v
+	}
+	return entry, nil
+}
+
+// deadLetter 串行写入死信输出，framed 为true时按长度前缀格式写入
+type deadLetter struct {
+	lock      sync.Mutex
+	w         io.Writer
+	serialize Serializer
+	framed    bool
+}
+
+func (d *deadLetter) write(entries []*logrus.Entry) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, entry := range entries {
+		buf, err := d.serialize(entry)
+		if err != nil {
+			return err
+		}
+		if d.framed {
+			buf = frame(buf)
+		} else {
+			buf = append(buf, '\n')
+		}
+		if _, err := d.w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DedupKeyHandle 返回条目去重的键
+type DedupKeyHandle func(*logrus.Entry) string
+
+// SetDedup 设置去重窗口，窗口内连续相同(消息与级别相同)的条目合并为一条，
+// 窗口结束时写入并附带 repeat_count 字段
+func SetDedup(window time.Duration) Option {
+	return func(o *options) {
+		o.fixed("SetDedup")
+		o.dedupWindow = window
+	}
+}
+
+// SetDedupKey 设置去重的键，默认为级别与消息
+func SetDedupKey(key DedupKeyHandle) Option {
+	return func(o *options) {
+		o.fixed("SetDedupKey")
+		o.dedupKey = key
+	}
+}
+
+func defaultDedupKey(entry *logrus.Entry) string {
+	return strconv.Itoa(int(entry.Level)) + ":" + entry.Message
+}
+
+// deduper 合并连续相同的条目
+type deduper struct {
+	lock    sync.Mutex
+	window  time.Duration
+	key     DedupKeyHandle
+	expired func(*logrus.Entry)
+	pending *logrus.Entry
+	pkey    string
+	count   int
+	gen     uint64
+	timer   *time.Timer
+}
+
+func newDeduper(window time.Duration, key DedupKeyHandle, expired func(*logrus.Entry)) *deduper {
+	if key == nil {
+		key = defaultDedupKey
+	}
+	return &deduper{window: window, key: key, expired: expired}
+}
+
+// add 添加条目，与暂存的条目相同时合并；否则暂存该条目并返回上一个暂存的条目
+func (d *deduper) add(entry *logrus.Entry) (prev *logrus.Entry, merged bool) {
+	key := d.key(entry)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.pending != nil && d.pkey == key {
+		d.count++
+		return nil, true
+	}
+
+	prev = d.take()
+	d.pending, d.pkey, d.count = entry, key, 1
+	gen := d.gen
+	d.timer = time.AfterFunc(d.window, func() {
+		d.expire(gen)
+	})
+	return prev, false
+}
+
+// take 取出暂存的条目，合并过的条目附带 repeat_count 字段
+func (d *deduper) take() *logrus.Entry {
+	entry := d.pending
+	if entry == nil {
+		return nil
+	}
+	d.timer.Stop()
+	if d.count > 1 {
+		entry.Data["repeat_count"] = d.count
+	}
+	d.pending, d.pkey, d.count = nil, "", 0
+	d.gen++
+	return entry
+}
+
+func (d *deduper) expire(gen uint64) {
+	d.lock.Lock()
+	if gen != d.gen {
+		d.lock.Unlock()
+		return
+	}
+	entry := d.take()
+	d.lock.Unlock()
+
+	if entry != nil {
+		d.expired(entry)
+	}
+}
+
+// flush 取出暂存的条目
+func (d *deduper) flush() *logrus.Entry {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.take()
+}
+
+// dedupe 对条目去重，返回需要入队的条目
+func (h *Hook) dedupe(entry *logrus.Entry) *logrus.Entry {
+	prev, merged := h.dedup.add(entry)
+	if merged {
+		atomic.AddUint64(&h.stats.deduped, 1)
+		h.drop(entry, DropDeduped)
+	}
+	return prev
+}
+
+// enqueueExpired 去重窗口结束后将暂存的条目入队
+func (h *Hook) enqueueExpired(entry *logrus.Entry) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if h.terminated {
+		return
+	}
+	h.enqueue(entry)
+}
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// pending 按推送顺序为任务编号，记录未执行完成的任务
+type pending struct {
+	lock    sync.Mutex
+	cond    *sync.Cond
+	seq     uint64
+	low     uint64
+	running map[uint64]struct{}
+}
+
+func newPending() *pending {
+	p := &pending{low: 1, running: make(map[uint64]struct{})}
+	p.cond = sync.NewCond(&p.lock)
+	return p
+}
+
+// push 记录推送的任务，返回任务的编号
+func (p *pending) push() uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.seq++
+	p.running[p.seq] = struct{}{}
+	return p.seq
+}
+
+// done 记录任务执行完成，low 前移到最早未完成的任务
+func (p *pending) done(seq uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.running, seq)
+	for p.low <= p.seq {
+		if _, ok := p.running[p.low]; ok {
+			break
+		}
+		p.low++
+	}
+	p.cond.Broadcast()
+}
+
+// wait 等待调用时已推送的任务执行完成，之后推送的任务不在等待之列
+func (p *pending) wait() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for seq := p.seq; p.low <= seq; {
+		p.cond.Wait()
+	}
+}
+
+// Drain 等待调用时队列中已有的条目(包括溢出文件中的条目)写入完成，并写入剩余的批量条目，
+// 调用后新记录的条目不在等待之列，持续记录日志时同样会返回。
+// 与Flush不同，Drain不停止工作线程，之后仍可继续记录日志
+func (h *Hook) Drain() {
+	h.Start()
+	var spilled int
+	if h.spill != nil {
+		spilled, _ = h.spill.progress()
+	}
+	for {
+		h.replaySpill()
+		h.pending.wait()
+		if h.spill == nil || h.isTerminated() {
+			break
+		}
+		// 调用时已溢出的条目全部取出后，写入这些条目的任务均已推送
+		if _, read := h.spill.progress(); read >= spilled {
+			break
+		}
+	}
+	if h.batch != nil {
+		h.batch.flush(h.options().ctx)
+	}
+}
+
+func (h *Hook) isTerminated() bool {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.terminated
+}
+
+// ForceFlush 在调用方的goroutine中立即写入缓冲区(包括溢出文件)中已有的条目与剩余的批量条目，不停止工作线程，
+// 适用于测试与崩溃处理等无法等待工作线程的场景。与Drain不同，ForceFlush不经过工作线程，
+// 工作线程正在写入的条目不在等待之列。ctx 与基础上下文一起用于写入与重试退避，取消或超时时中止写入并返回ctx的错误
+func (h *Hook) ForceFlush(ctx context.Context) error {
+	wctx, cancel := mergeContext(ctx, h.options().ctx)
+	defer cancel()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entry := h.buf.pop()
+		if entry == nil {
+			if h.spilled() == 0 || h.isTerminated() {
+				break
+			}
+			if h.replaySpill(); h.buf.len() == 0 {
+				break
+			}
+			continue
+		}
+		h.checkWaterMarks()
+		h.forceExec(wctx, entry)
+	}
+	if h.batch != nil {
+		h.batch.flush(wctx)
+	}
+	return ctx.Err()
+}
+
+// forceExec 在调用方的goroutine中使用ctx写入条目
+func (h *Hook) forceExec(ctx context.Context, entry *logrus.Entry) {
+	atomic.AddInt64(&h.stats.active, 1)
+	defer atomic.AddInt64(&h.stats.active, -1)
+	defer h.recoverWorker(entry)
+	h.exec(ctx, entry)
+}
+
+// mergeContext 返回在ctx或base取消时取消的上下文，带有ctx的值
+func mergeContext(ctx, base context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	if base.Done() != nil {
+		go func() {
+			select {
+			case <-base.Done():
+				cancel()
+			case <-merged.Done():
+			}
+		}()
+	}
+	return merged, cancel
+}
+
+// baseContext 返回钩子的基础上下文
+func (h *Hook) baseContext() context.Context {
+	return h.options().ctx
+}
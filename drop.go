@@ -0,0 +1,39 @@
+package logger
+
+import "github.com/sirupsen/logrus"
+
+// 条目被丢弃的原因
+const (
+	// DropOverflow 队列已满，按溢出策略丢弃或被高优先级条目淘汰
+	DropOverflow = "overflow"
+	// DropSampled 未被采样
+	DropSampled = "sampled"
+	// DropDeduped 与去重窗口内的条目合并
+	DropDeduped = "deduped"
+	// DropFiltered 过滤器返回nil
+	DropFiltered = "filtered"
+	// DropSizeLimit 截断字段后文档仍超过大小限制
+	DropSizeLimit = "size_limit"
+	// DropSpillError 写入溢出文件失败
+	DropSpillError = "spill_error"
+	// DropClosed 钩子已关闭且未设置死信
+	DropClosed = "closed"
+)
+
+// DropHandle 条目被丢弃时的回调，reason 为丢弃的原因
+type DropHandle func(entry *logrus.Entry, reason string)
+
+// SetDropHandler 设置条目被丢弃时的回调，用于统计与告警。回调可能在Fire或工作线程中执行，
+// 应尽快返回且不能持有条目
+func SetDropHandler(handler DropHandle) Option {
+	return func(o *options) {
+		o.dropHandler = handler
+	}
+}
+
+// drop 调用丢弃回调
+func (h *Hook) drop(entry *logrus.Entry, reason string) {
+	if handler := h.options().dropHandler; handler != nil && entry != nil {
+		handler(entry, reason)
+	}
+}
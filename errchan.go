@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"sync/atomic"
+)
+
+// errorsBuffer 错误通道的缓冲大小
+const errorsBuffer = 100
+
+// errChan 写入错误的通道，第一次调用Errors时创建
+type errChan struct {
+	ch     chan error
+	closed bool
+}
+
+// Errors 返回写入错误的通道，通道已满时丢弃错误并计入Stats.ErrorsDropped，钩子关闭时关闭通道。
+// 与SetErrorHandler同时使用时两者都会收到错误
+func (h *Hook) Errors() <-chan error {
+	h.errLock.Lock()
+	defer h.errLock.Unlock()
+	if h.errs.ch == nil {
+		h.errs.ch = make(chan error, errorsBuffer)
+		if h.errs.closed {
+			close(h.errs.ch)
+		}
+	}
+	return h.errs.ch
+}
+
+// sendError 不阻塞地发送写入错误
+func (h *Hook) sendError(err error) {
+	h.errLock.RLock()
+	defer h.errLock.RUnlock()
+	if h.errs.ch == nil || h.errs.closed {
+		return
+	}
+	select {
+	case h.errs.ch <- err:
+	default:
+		atomic.AddUint64(&h.stats.errorsDropped, 1)
+	}
+}
+
+// closeErrors 关闭错误通道
+func (h *Hook) closeErrors() {
+	h.errLock.Lock()
+	defer h.errLock.Unlock()
+	if h.errs.closed {
+		return
+	}
+	h.errs.closed = true
+	if h.errs.ch != nil {
+		close(h.errs.ch)
+	}
+}
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetErrorFieldExpand 设置默认文档中错误类型的字段(如 WithError 添加的 error 字段)是否保存为
+// {message, type} 的嵌套结构，默认只保存错误信息 err.Error()
+func SetErrorFieldExpand(errorFieldExpand bool) Option {
+	return func(o *options) {
+		o.errorFieldExpand = errorFieldExpand
+	}
+}
+
+// errorFields 将错误类型的字段转换为错误信息，错误类型通常没有导出字段，直接写入时会保存为空文档
+func errorFields(item bson.M, expand bool) {
+	for k, v := range item {
+		err, ok := v.(error)
+		if !ok {
+			continue
+		}
+		if expand {
+			item[k] = bson.M{"message": errorMessage(err), "type": fmt.Sprintf("%T", err)}
+		} else {
+			item[k] = errorMessage(err)
+		}
+	}
+}
+
+// errorMessage 返回错误信息，值为nil的指针类型错误调用Error时可能引发panic
+func errorMessage(err error) (msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprintf("%T", err)
+		}
+	}()
+	return err.Error()
+}
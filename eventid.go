@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+)
+
+// SetGenerateEventID 设置是否为每个条目生成唯一的事件ID，便于跨系统关联同一日志事件，默认关闭。
+// 事件ID只是可查询的字段，与SetIDGenerator生成的文档 _id 无关
+func SetGenerateEventID(generateEventID bool) Option {
+	return func(o *options) {
+		o.generateEventID = generateEventID
+	}
+}
+
+// SetEventIDField 设置保存事件ID的字段名称，默认为event_id
+func SetEventIDField(eventIDField string) Option {
+	return func(o *options) {
+		if eventIDField == "" {
+			return
+		}
+		o.eventIDField = eventIDField
+	}
+}
+
+var (
+	// eventIDPrefix 进程启动时随机生成的前缀，与递增的序号组成事件ID
+	eventIDPrefix [10]byte
+	eventIDSeq    uint64
+)
+
+func init() {
+	if _, err := rand.Read(eventIDPrefix[:]); err != nil {
+		binary.BigEndian.PutUint64(eventIDPrefix[:], uint64(time.Now().UnixNano()))
+	}
+}
+
+// newEventID 生成 8-4-4-4-12 格式的事件ID，不需要每次读取随机数
+func newEventID() string {
+	var id [16]byte
+	copy(id[:], eventIDPrefix[:])
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], atomic.AddUint64(&eventIDSeq, 1))
+	copy(id[10:], seq[2:])
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+	return string(buf[:])
+}
@@ -1,20 +1,95 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
 	"github.com/pm-esd/mongodb"
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
+// errNoDatabase 客户端未提供驱动层的数据库
+var errNoDatabase = errors.New("mongo client does not expose the driver database")
+
 // ExecCloser 将logrus条目写入数据库并关闭数据库
 type ExecCloser interface {
 	Exec(entry *logrus.Entry) error
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// ContextExecer 使用上下文将logrus条目写入数据库，上下文取消时应中止写入
+type ContextExecer interface {
+	ExecContext(ctx context.Context, entry *logrus.Entry) error
+}
+
+// BatchExecer 将一批logrus条目写入数据库
+type BatchExecer interface {
+	BatchExec(entries []*logrus.Entry) error
+}
+
+// BatchContextExecer 使用上下文将一批logrus条目写入数据库，上下文取消时应中止写入
+type BatchContextExecer interface {
+	BatchExecContext(ctx context.Context, entries []*logrus.Entry) error
+}
+
+// optionsBinder 需要读取钩子参数的Exec，钩子创建时绑定读取当前参数的函数
+type optionsBinder interface {
+	bind(cfg func() *options)
+}
+
+// CollectionRouter 根据条目返回写入的集合名称，返回空字符串时使用默认集合
+type CollectionRouter func(*logrus.Entry) string
+
+// SetCollectionRouter 设置默认Exec的集合路由，在过滤器之后执行
+func SetCollectionRouter(router CollectionRouter) Option {
+	return func(o *options) {
+		o.router = router
+	}
+}
+
+// SetFieldNames 设置默认Exec文档字段名称的映射(如 level -> severity)，未映射的字段保持不变
+func SetFieldNames(fieldNames map[string]string) Option {
+	return func(o *options) {
+		o.fieldNames = fieldNames
+	}
+}
+
+// DocumentBuilder 根据条目构建写入数据库的文档
+type DocumentBuilder func(*logrus.Entry) interface{}
+
+// SetDocumentBuilder 设置默认Exec的文档构建器，返回值即为写入的文档
+func SetDocumentBuilder(builder DocumentBuilder) Option {
+	return func(o *options) {
+		o.documentBuilder = builder
+	}
+}
+
+// SetCallerStructured 设置默认文档是否以 caller: {file, line, func} 的嵌套结构保存调用方，
+// 默认保存为 file("path:line") 与 func 两个字段
+func SetCallerStructured(callerStructured bool) Option {
+	return func(o *options) {
+		o.callerStructured = callerStructured
+	}
 }
 
 type defaultExec struct {
-	sess     *mongodb.MongoDBClient
-	cName    string
-	canClose bool
+	sess          *mongodb.MongoDBClient
+	coll          *mongo.Collection
+	cName         string
+	canClose      bool
+	cfg           func() *options
+	collisionOnce sync.Once
+	setupOnce     sync.Once
+	reconn        reconnectState
+	wc            *writeconcern.WriteConcern
+	levelWC       map[logrus.Level]*writeconcern.WriteConcern
+	colls         collectionCache
 }
 
 // NewExec create an exec instance
@@ -35,21 +110,258 @@ func NewExecWithURL(sess *mongodb.MongoDBClient, cName string) ExecCloser {
 	}
 }
 
-func (e *defaultExec) Exec(entry *logrus.Entry) error {
+// NewExecWithCollection 使用已配置的驱动层集合创建Exec，沿用集合的读写偏好等参数，
+// 关闭钩子时不断开集合所属的客户端
+func NewExecWithCollection(coll *mongo.Collection) ExecCloser {
+	return &defaultExec{
+		coll:  coll,
+		cName: coll.Name(),
+	}
+}
+
+// NewExecWithDatabase 使用驱动层客户端写入dbName数据库的cName集合，同一客户端可用于多个数据库，
+// 关闭钩子时不断开客户端
+func NewExecWithDatabase(client *mongo.Client, dbName, cName string) ExecCloser {
+	return NewExecWithCollection(client.Database(dbName).Collection(cName))
+}
+
+func (e *defaultExec) bind(cfg func() *options) {
+	e.cfg = cfg
+	e.setup(cfg())
+}
+
+// database 返回驱动层的数据库，用于建立索引等客户端未封装的操作
+func (e *defaultExec) database() (*mongo.Database, error) {
+	if e.coll != nil {
+		return e.coll.Database(), nil
+	}
+	if e.sess != nil {
+		if db := e.sess.Database(); db != nil {
+			return db, nil
+		}
+	}
+	return nil, errNoDatabase
+}
+
+// cancellable 客户端未提供驱动层的数据库时通过客户端写入，写入无法被上下文中止
+func (e *defaultExec) cancellable(batch bool) bool {
+	_, err := e.database()
+	return err == nil
+}
+
+// options 返回绑定的钩子参数，未绑定时返回默认参数
+func (e *defaultExec) options() *options {
+	if e.cfg == nil {
+		return &defaultOptions
+	}
+	return e.cfg()
+}
+
+// document 构建写入的文档，设置了文档构建器时使用构建器
+func (e *defaultExec) document(entry *logrus.Entry) interface{} {
+	if builder := e.options().documentBuilder; builder != nil {
+		return builder(entry)
+	}
+	return e.defaultDocument(entry)
+}
+
+// defaultDocument 默认的文档结构
+func (e *defaultExec) defaultDocument(entry *logrus.Entry) bson.M {
+	o := e.options()
 	item := make(bson.M)
 
 	for k, v := range entry.Data {
 		item[k] = v
 	}
+	errorFields(item, o.errorFieldExpand)
+	invalidFields(item)
+	coerce(item, o.coercions)
+	e.nest(item, entry)
 
-	item["level"] = entry.Level
-	item["message"] = entry.Message
-	item["created"] = entry.Time.Unix()
+	prefix := o.reservedPrefix
+	levelFields(item, prefix, o.levelFormat, entry.Level)
+	reserve(item, prefix, "message", entry.Message)
+	reserve(item, prefix, "created", entry.Time.Unix())
+	// 以BSON日期保存条目时间，便于按时间范围查询与建立TTL索引
+	t := entry.Time
+	if t.IsZero() {
+		t = o.clock()
+	}
+	reserve(item, prefix, o.timeField, t)
+	if o.callerStructured && entry.HasCaller() {
+		// 与用户字段同名时钩子注入的调用方字段带有保留前缀，只删除钩子注入的字段
+		injected := [][2]string{{"file", callerFile(entry.Caller)}, {"func", entry.Caller.Function}}
+		for _, field := range injected {
+			key, value := field[0], field[1]
+			if v, ok := item[prefix+key]; ok && v == value && item[key] != value {
+				key = prefix + key
+			}
+			delete(item, key)
+		}
+		reserve(item, prefix, "caller", bson.M{
+			"file": entry.Caller.File,
+			"line": entry.Caller.Line,
+			"func": entry.Caller.Function,
+		})
+	}
+	storeFormatted(item, o, entry)
+	timeSeriesMeta(item, o.timeSeries)
+	if len(o.includeFields) == 0 {
+		for _, k := range o.excludeFields {
+			delete(item, k)
+		}
+	}
+	doc := e.rename(item)
+	// 重命名后压缩，编码标记使用重命名后的字段名称
+	if o.compressThreshold > 0 {
+		if err := compress(doc, o.compressThreshold); err != nil {
+			o.warn("Compress error: %s", err.Error())
+		}
+	}
+	if generator := o.idGenerator; generator != nil {
+		if id := generator(entry); id != nil {
+			doc["_id"] = id
+		}
+	}
+	return doc
+}
+
+// rename 按字段名称映射重命名文档字段，映射后的字段优先于同名的原字段
+func (e *defaultExec) rename(item bson.M) bson.M {
+	fieldNames := e.options().fieldNames
+	if len(fieldNames) == 0 {
+		return item
+	}
+
+	doc := make(bson.M, len(item))
+	for k, v := range item {
+		if _, ok := fieldNames[k]; !ok {
+			doc[k] = v
+		}
+	}
+	for k, v := range item {
+		target, ok := fieldNames[k]
+		if !ok {
+			continue
+		}
+		if _, ok := doc[target]; ok {
+			e.collisionOnce.Do(func() {
+				e.options().warn("Field name collision: %s", target)
+			})
+		}
+		doc[target] = v
+	}
+	return doc
+}
+
+func (e *defaultExec) Exec(entry *logrus.Entry) error {
+	return e.ExecContext(context.Background(), entry)
+}
+
+// ExecContext 使用上下文写入条目，上下文可以取消时通过驱动层集合写入，取消或超时将中止写入
+func (e *defaultExec) ExecContext(ctx context.Context, entry *logrus.Entry) error {
+	if err := e.ensureConnected(ctx); err != nil {
+		return err
+	}
+	e.ensureCollection()
+	item := e.document(entry)
 
 	// _, err := e.sess.Collection(e.cName).InsertOne(context.Background(), item)
-	_, err := e.sess.Collection(e.cName).InsertOne(item)
-	if err != nil {
+	if err := e.save(ctx, e.target(entry), item); err != nil {
+		e.checkConnection(err)
 		return err
 	}
 	return nil
 }
+
+func (e *defaultExec) BatchExec(entries []*logrus.Entry) error {
+	return e.BatchExecContext(context.Background(), entries)
+}
+
+// BatchExecContext 使用上下文批量写入条目，上下文可以取消时通过驱动层集合写入
+func (e *defaultExec) BatchExecContext(ctx context.Context, entries []*logrus.Entry) error {
+	if err := e.ensureConnected(ctx); err != nil {
+		return err
+	}
+	e.ensureCollection()
+
+	var targets []target
+	groups := make(map[target][]interface{})
+	indices := make(map[target][]int)
+	for i, entry := range entries {
+		t := e.target(entry)
+		if _, ok := groups[t]; !ok {
+			targets = append(targets, t)
+		}
+		groups[t] = append(groups[t], e.document(entry))
+		indices[t] = append(indices[t], i)
+	}
+
+	return writeGroups(len(entries), targets, indices, func(t target) error {
+		err := e.saveMany(ctx, t, groups[t])
+		if err != nil {
+			e.checkConnection(err)
+		}
+		return err
+	})
+}
+
+// writeGroups 依次写入各集合的条目，indices 为各集合的条目在整批中的位置。
+// 部分条目失败时返回以整批位置表示的BatchError，全部失败时返回首个错误(不含BatchError)
+func writeGroups(n int, targets []target, indices map[target][]int, write func(target) error) error {
+	var failed []int
+	var firstErr error
+	for i, t := range targets {
+		err := write(t)
+		if err == nil {
+			continue
+		}
+		var batchErr *BatchError
+		partial := errors.As(err, &batchErr)
+		if partial {
+			err = batchErr.Err
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if partial {
+			for _, index := range batchErr.Indices {
+				failed = append(failed, indices[t][index])
+			}
+			continue
+		}
+		// 整组写入失败时不再写入其余的集合
+		for _, t := range targets[i:] {
+			failed = append(failed, indices[t]...)
+		}
+		break
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+	if len(failed) == n {
+		return firstErr
+	}
+	sort.Ints(failed)
+	return &BatchError{Indices: failed, Err: firstErr}
+}
+
+// Ping 执行 ping 命令检查数据库是否可用
+func (e *defaultExec) Ping(ctx context.Context) error {
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	return db.RunCommand(ctx, bson.D{{Key: "ping", Value: 1}}).Err()
+}
+
+func (e *defaultExec) Close() error {
+	if !e.canClose {
+		return nil
+	}
+	if e.sess == nil {
+		return nil
+	}
+	return e.sess.Close()
+}
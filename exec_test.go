@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pm-esd/mongodb"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWriteGroupsIndices(t *testing.T) {
+	a, b := target{name: "a"}, target{name: "b"}
+	errWrite := errors.New("write failed")
+	indices := map[target][]int{a: {0, 2, 4}, b: {1, 3}}
+	tests := []struct {
+		errs    map[target]error
+		indices []int
+		err     error
+	}{
+		{map[target]error{}, nil, nil},
+		{map[target]error{a: &BatchError{Indices: []int{1}, Err: errWrite}}, []int{2}, errWrite},
+		{map[target]error{
+			a: &BatchError{Indices: []int{0, 2}, Err: errWrite},
+			b: &BatchError{Indices: []int{0}, Err: errors.New("other")},
+		}, []int{0, 1, 4}, errWrite},
+		{map[target]error{b: errWrite}, []int{1, 3}, errWrite},
+		// 全部失败时不返回各组位置的BatchError
+		{map[target]error{
+			a: &BatchError{Indices: []int{0, 1, 2}, Err: errWrite},
+			b: &BatchError{Indices: []int{0, 1}, Err: errWrite},
+		}, nil, errWrite},
+		{map[target]error{a: errWrite}, nil, errWrite},
+	}
+	for i, tt := range tests {
+		var written []target
+		err := writeGroups(5, []target{a, b}, indices, func(t target) error {
+			written = append(written, t)
+			return tt.errs[t]
+		})
+		var batchErr *BatchError
+		if errors.As(err, &batchErr) {
+			if !reflect.DeepEqual(batchErr.Indices, tt.indices) || batchErr.Err != tt.err {
+				t.Fatalf("case %d: BatchError{%v, %v}, want {%v, %v}", i, batchErr.Indices, batchErr.Err, tt.indices, tt.err)
+			}
+			continue
+		}
+		if tt.indices != nil || err != tt.err {
+			t.Fatalf("case %d: error = %v, want indices %v of %v", i, err, tt.indices, tt.err)
+		}
+		if tt.errs[a] == errWrite && len(written) != 1 {
+			t.Fatalf("case %d: wrote %v after the first group failed", i, written)
+		}
+	}
+}
+
+func TestDocumentTimeIsDate(t *testing.T) {
+	now := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		field string
+		time  time.Time
+		want  time.Time
+	}{
+		{"", now.Add(-time.Hour), now.Add(-time.Hour)},
+		{"", time.Time{}, now},
+		{"ts", now.Add(-time.Minute), now.Add(-time.Minute)},
+	}
+	for i, tt := range tests {
+		opts := defaultOptions
+		opts.clock = func() time.Time { return now }
+		if tt.field != "" {
+			SetTimeField(tt.field)(&opts)
+		}
+		e := &defaultExec{cfg: func() *options { return &opts }}
+		entry := testEntry(logrus.InfoLevel, "a")
+		entry.Time = tt.time
+		doc := e.defaultDocument(entry)
+		if v, ok := doc[opts.timeField].(time.Time); !ok || !v.Equal(tt.want) {
+			t.Fatalf("case %d: %s = %#v, want %s", i, opts.timeField, doc[opts.timeField], tt.want)
+		}
+	}
+}
+
+// nilError 值为nil时调用Error会引发panic的错误类型
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+
+func TestWithErrorDocument(t *testing.T) {
+	errWrite := errors.New("write failed")
+	tests := []struct {
+		expand bool
+		err    error
+		want   interface{}
+	}{
+		{false, errWrite, "write failed"},
+		{true, errWrite, bson.M{"message": "write failed", "type": "*errors.errorString"}},
+		{false, (*nilError)(nil), "*logger.nilError"},
+		{true, &nilError{"custom"}, bson.M{"message": "custom", "type": "*logger.nilError"}},
+	}
+	for i, tt := range tests {
+		opts := defaultOptions
+		SetErrorFieldExpand(tt.expand)(&opts)
+		e := &defaultExec{cfg: func() *options { return &opts }}
+		entry := logrus.NewEntry(logrus.New()).WithError(tt.err)
+		entry.Message = "a"
+		doc := e.defaultDocument(entry)
+		if !reflect.DeepEqual(doc[logrus.ErrorKey], tt.want) {
+			t.Fatalf("case %d: error = %#v, want %#v", i, doc[logrus.ErrorKey], tt.want)
+		}
+
+		// 错误没有导出字段，直接编码时会保存为空文档
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		var decoded bson.M
+		if err := bson.Unmarshal(raw, &decoded); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if decoded[logrus.ErrorKey] == nil || reflect.DeepEqual(decoded[logrus.ErrorKey], bson.M{}) {
+			t.Fatalf("case %d: stored error = %#v", i, decoded[logrus.ErrorKey])
+		}
+	}
+}
+
+func TestWithErrorDeadLetter(t *testing.T) {
+	for _, err := range []error{errors.New("write failed"), (*nilError)(nil)} {
+		entry := logrus.NewEntry(logrus.New()).WithError(err)
+		buf, e := marshalEntry(entry)
+		if e != nil {
+			t.Fatal(e)
+		}
+		got, e := unmarshalEntry(buf)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if want := errorMessage(err); got.Data[logrus.ErrorKey] != want {
+			t.Fatalf("error = %v, want %s", got.Data[logrus.ErrorKey], want)
+		}
+	}
+}
+
+func TestCloseNewExec(t *testing.T) {
+	if err := NewExec(new(mongodb.MongoDBClient), "logs").Close(); err != nil {
+		t.Fatalf("Close = %v", err)
+	}
+	if err := NewExec(nil, "logs").Close(); err != nil {
+		t.Fatalf("Close without client = %v", err)
+	}
+}
+
+func TestNewExecWithoutDatabase(t *testing.T) {
+	e := NewExec(new(mongodb.MongoDBClient), "logs").(*defaultExec)
+	if _, err := e.database(); err != errNoDatabase {
+		t.Fatalf("database = %v, want %v", err, errNoDatabase)
+	}
+	if err := e.Ping(context.Background()); err != errNoDatabase {
+		t.Fatalf("Ping = %v, want %v", err, errNoDatabase)
+	}
+}
+
+func TestCompressMarkerRenamed(t *testing.T) {
+	opts := defaultOptions
+	SetFieldNames(map[string]string{"message": "msg"})(&opts)
+	SetCompressThreshold(4)(&opts)
+	e := &defaultExec{cfg: func() *options { return &opts }}
+	doc := e.defaultDocument(testEntry(logrus.InfoLevel, "compressed message"))
+
+	// 编码标记与重命名后的字段对应
+	if _, ok := doc["message"+encodingSuffix]; ok {
+		t.Fatalf("marker kept the original field name: %v", doc)
+	}
+	encoding, _ := doc["msg"+encodingSuffix].(string)
+	s, err := DecodeField(doc["msg"], encoding)
+	if err != nil || s != "compressed message" {
+		t.Fatalf("DecodeField = %q, %v", s, err)
+	}
+}
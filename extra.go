@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// normalizeExtra 返回可写入文档的扩展参数，error 转换为错误信息，无法序列化为BSON的值被移除并记录
+func (o *options) normalizeExtra(extra map[string]interface{}) map[string]interface{} {
+	if extra == nil {
+		return nil
+	}
+	normalized := make(map[string]interface{}, len(extra))
+	for k, v := range extra {
+		v, ok := normalizeValue(v)
+		if !ok {
+			o.invalidExtra = append(o.invalidExtra[:len(o.invalidExtra):len(o.invalidExtra)], fmt.Sprintf("%s (%T)", k, extra[k]))
+			continue
+		}
+		normalized[k] = v
+	}
+	return normalized
+}
+
+// normalizeValue 将 error 转换为错误信息，值无法序列化为BSON时返回false
+func normalizeValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case nil, bool, string, []byte, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64, time.Time, time.Duration:
+		return v, true
+	case error:
+		return errorMessage(v), true
+	}
+	return v, marshalable(reflect.ValueOf(v), 0)
+}
+
+// maxMarshalDepth 检查嵌套值的最大深度，超过时视为可序列化
+const maxMarshalDepth = 8
+
+// marshalable 判断值能否序列化为BSON，通道、函数、复数与非字符串键的映射不能序列化
+func marshalable(v reflect.Value, depth int) bool {
+	if depth > maxMarshalDepth {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil() || marshalable(v.Elem(), depth+1)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !marshalable(v.Index(i), depth+1) {
+				return false
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if !marshalable(iter.Value(), depth+1) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// invalidFields 移除文档中无法序列化为BSON的字段，被移除的字段名保存在 _invalid_fields 中
+func invalidFields(item bson.M) {
+	var invalid []string
+	for k, v := range item {
+		if _, ok := normalizeValue(v); !ok {
+			invalid = append(invalid, k)
+			delete(item, k)
+		}
+	}
+	if len(invalid) > 0 {
+		item["_invalid_fields"] = invalid
+	}
+}
+
+// reportInvalid 通过内部日志报告设置时被忽略的级别名称与扩展参数
+func (o *options) reportInvalid() {
+	for _, name := range o.invalidLevels {
+		o.warn("Invalid level name: %q", name)
+	}
+	for _, field := range o.invalidExtra {
+		o.warn("Invalid extra value: %s", field)
+	}
+	o.invalidLevels, o.invalidExtra = nil, nil
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// SetIncludeFields 设置只保存的字段，未列出的字段不写入数据库。
+// level、message和时间字段始终保存；与SetExcludeFields同时设置时只使用包含列表
+func SetIncludeFields(keys ...string) Option {
+	return func(o *options) {
+		o.includeFields = append(o.includeFields[:len(o.includeFields):len(o.includeFields)], keys...)
+	}
+}
+
+// SetExcludeFields 设置不保存的字段。列出level、message、created或时间字段时，
+// 默认Exec的文档中也不保存这些字段；设置了SetIncludeFields时不生效
+func SetExcludeFields(keys ...string) Option {
+	return func(o *options) {
+		o.excludeFields = append(o.excludeFields[:len(o.excludeFields):len(o.excludeFields)], keys...)
+	}
+}
+
+// selectFields 按包含或排除列表删除复制后条目中的字段
+func (h *Hook) selectFields(entry *logrus.Entry) {
+	if include := h.options().includeFields; len(include) > 0 {
+		for k := range entry.Data {
+			if !containsString(include, k) {
+				delete(entry.Data, k)
+			}
+		}
+		return
+	}
+	for _, k := range h.options().excludeFields {
+		delete(entry.Data, k)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
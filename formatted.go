@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetStoreFormatted 设置默认文档是否以formatter格式化条目，并将格式化后的文本(去掉末尾换行)保存到field字段，
+// 便于按日志原文检索。格式化失败时不保存该字段，并将 _format_error 设置为true
+func SetStoreFormatted(formatter logrus.Formatter, field string) Option {
+	return func(o *options) {
+		o.formatter = formatter
+		o.formattedField = field
+	}
+}
+
+// format 格式化条目，使用条目的副本避免写入logrus的输出缓冲区
+func format(formatter logrus.Formatter, entry *logrus.Entry) (s string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("formatter panic")
+		}
+	}()
+	e := *entry
+	e.Buffer = nil
+	buf, err := formatter.Format(&e)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(buf), "\n"), nil
+}
+
+// storeFormatted 将格式化后的文本写入文档
+func storeFormatted(item bson.M, o *options, entry *logrus.Entry) {
+	if o.formatter == nil || o.formattedField == "" {
+		return
+	}
+	s, err := format(o.formatter, entry)
+	if err != nil {
+		item["_format_error"] = true
+		return
+	}
+	reserve(item, o.reservedPrefix, o.formattedField, s)
+}
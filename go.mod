@@ -5,6 +5,7 @@ go 1.14
 require (
 	github.com/pm-esd/mongodb v1.3.3
 	github.com/pm-esd/queue v1.0.1
+	github.com/prometheus/client_golang v1.7.1
 	github.com/sirupsen/logrus v1.6.0
 	go.mongodb.org/mongo-driver v1.3.3
 )
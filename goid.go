@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// SetIncludeGoroutineID 设置是否在条目中保存记录日志的goroutine编号(goid)，默认关闭
+func SetIncludeGoroutineID(includeGoroutineID bool) Option {
+	return func(o *options) {
+		o.includeGoroutineID = includeGoroutineID
+	}
+}
+
+// goroutineID 从当前goroutine的栈信息中解析编号，解析失败时返回0
+func goroutineID() uint64 {
+	var buf [64]byte
+	return parseGoroutineID(buf[:runtime.Stack(buf[:], false)])
+}
+
+// parseGoroutineID 从栈信息的首行("goroutine 123 [running]:")中解析编号，解析失败时返回0
+func parseGoroutineID(b []byte) uint64 {
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
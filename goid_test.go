@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseGoroutineID(t *testing.T) {
+	tests := []struct {
+		stack string
+		want  uint64
+	}{
+		{"goroutine 1 [running]:\nmain.main()", 1},
+		{"goroutine 18446744073709551615 [running]:", 18446744073709551615},
+		{"goroutine 42", 42},
+		{"goroutine 18446744073709551616 [running]:", 0},
+		{"goroutine -1 [running]:", 0},
+		{"goroutine  [running]:", 0},
+		{"goroutine x [running]:", 0},
+		{"thread 7 [running]:", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if id := parseGoroutineID([]byte(tt.stack)); id != tt.want {
+			t.Errorf("parseGoroutineID(%q) = %d, want %d", tt.stack, id, tt.want)
+		}
+	}
+}
+
+func TestIncludeGoroutineID(t *testing.T) {
+	exec := NewMemoryExec()
+	h := New(SetExec(exec), SetSync(true), SetIncludeGoroutineID(true))
+	defer h.Close()
+
+	var ids []uint64
+	for i := 0; i < 2; i++ {
+		done := make(chan uint64)
+		go func() {
+			h.Fire(testEntry(logrus.InfoLevel, "a"))
+			done <- goroutineID()
+		}()
+		ids = append(ids, <-done)
+	}
+	entries := exec.Entries()
+	if len(entries) != 2 || ids[0] == 0 || ids[0] == ids[1] {
+		t.Fatalf("%d entries written by goroutines %v", len(entries), ids)
+	}
+	for i, entry := range entries {
+		if id := entry.Data["goid"]; id != ids[i] {
+			t.Fatalf("entry %d: goid = %v, want %d", i, id, ids[i])
+		}
+	}
+}
@@ -1,44 +1,172 @@
 package logger
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pm-esd/mongodb"
-	"github.com/pm-esd/queue"
 	"github.com/sirupsen/logrus"
 )
 
 var defaultOptions = options{
-	maxQueues:  512,
-	maxWorkers: 2,
+	maxQueues:      512,
+	maxWorkers:     2,
+	flushInterval:  time.Second,
+	timeField:      "time",
+	stackDepth:     32,
+	reconnect:      true,
+	clock:          time.Now,
+	recoverWorker:  true,
+	resolveCaller:  true,
+	reservedPrefix: "_",
+	serializer:     marshalEntry,
+	eventIDField:   "event_id",
+	autoStart:      true,
+	ctx:            context.Background(),
 	levels: []logrus.Level{
+		logrus.PanicLevel,
 		logrus.FatalLevel,
 		logrus.ErrorLevel,
 		logrus.WarnLevel,
 		logrus.InfoLevel,
 		logrus.DebugLevel,
+		logrus.TraceLevel,
 	},
 	out: os.Stderr,
 }
 
+var (
+	// ErrClosed 钩子已关闭
+	ErrClosed = errors.New("mongo hook is closed")
+	// ErrFlushTimeout 等待日志队列为空超时
+	ErrFlushTimeout = errors.New("mongo hook flush timeout")
+	// ErrNoExec 未设置Exec
+	ErrNoExec = errors.New("mongo hook has no exec")
+	// ErrInvalidQueueSize 队列容量不是正数
+	ErrInvalidQueueSize = errors.New("mongo hook queue size must be positive")
+	// ErrInvalidWorkers 工作线程数不是正数，或伸缩的最小值大于最大值
+	ErrInvalidWorkers = errors.New("mongo hook worker count must be positive")
+	// ErrInvalidWaterMark 水位不在(0, 1]之间，或低水位高于高水位
+	ErrInvalidWaterMark = errors.New("mongo hook water mark must be in (0, 1]")
+	// ErrAppendOnlyUpsert 同时设置了只追加与upsert
+	ErrAppendOnlyUpsert = errors.New("mongo hook append-only is incompatible with upsert")
+	// ErrInvalidStackDepth 调用栈的最大帧数为负数
+	ErrInvalidStackDepth = errors.New("mongo hook stack depth must not be negative")
+	// ErrFixedOption Reconfigure传入了只能在创建时设置的参数
+	ErrFixedOption = errors.New("mongo hook option can only be set when the hook is created")
+)
+
 // FilterHandle 一个过滤器处理程序
 type FilterHandle func(*logrus.Entry) *logrus.Entry
 
 type options struct {
-	maxQueues  int
-	maxWorkers int
-	extra      map[string]interface{}
-	exec       ExecCloser
-	filter     FilterHandle
-	levels     []logrus.Level
-	out        io.Writer
+	maxQueues          int
+	maxWorkers         int
+	minWorkers         int
+	maxScaleWorkers    int
+	queue              JobQueue
+	batchSize          int
+	flushInterval      time.Duration
+	overflow           OverflowPolicy
+	priorityLevels     []logrus.Level
+	highWater          waterMark
+	lowWater           waterMark
+	ctx                context.Context
+	contextFields      map[interface{}]string
+	callerSkip         int
+	callerIgnore       []string
+	resolveCaller      bool
+	stackLevels        []logrus.Level
+	stackDepth         int
+	sync               bool
+	syncLevels         []logrus.Level
+	sampler            Sampler
+	sampleErrors       bool
+	auditKey           string
+	dedupWindow        time.Duration
+	dedupKey           DedupKeyHandle
+	enrichHost         bool
+	includeGoroutineID bool
+	reconnect          bool
+	latencyObserver    LatencyObserver
+	clock              func() time.Time
+	redactKeys         []string
+	redactPatterns     []redactPattern
+	cardinality        map[string]cardinalityLimit
+	includeFields      []string
+	excludeFields      []string
+	maxFieldBytes      int
+	maxDocBytes        int
+	maxAttempts        int
+	levelPolicies      map[logrus.Level]PolicyOptions
+	backoff            time.Duration
+	execTimeout        time.Duration
+	breakerFailures    int
+	breakerCooldown    time.Duration
+	extra              map[string]interface{}
+	levelExtra         map[logrus.Level]map[string]interface{}
+	traceExtractor     TraceExtractor
+	exec               ExecCloser
+	filters            []FilterHandle
+	preFilter          FilterHandle
+	dropHandler        DropHandle
+	levels             []logrus.Level
+	invalidLevels      []string
+	invalidExtra       []string
+	out                io.Writer
+	internalLogger     func(string)
+	reentry            *reentry
+	writeConcern       interface{}
+	errorHandler       ErrorHandle
+	resultHook         ResultHandle
+	recoverWorker      bool
+	deadLetter         io.Writer
+	sharedDead         *deadLetter
+	fixedOptions       []string
+	serializer         Serializer
+	deserializer       Deserializer
+	spillFile          string
+	router             CollectionRouter
+	collectionTemplate CollectionTemplate
+	ttl                time.Duration
+	timeField          string
+	cappedSize         int64
+	cappedMaxDocs      int64
+	fieldNames         map[string]string
+	nestFields         map[string]string
+	poolEntries        bool
+	autoStart          bool
+	generateEventID    bool
+	errorFieldExpand   bool
+	formatter          logrus.Formatter
+	formattedField     string
+	eventIDField       string
+	coercions          map[string]func(interface{}) interface{}
+	levelFormat        LevelFormat
+	reservedPrefix     string
+	documentBuilder    DocumentBuilder
+	idGenerator        IDGenerator
+	upsert             bool
+	appendOnly         bool
+	timeSeries         *timeSeries
+	tenantField        string
+	tenantPrefix       string
+	callerStructured   bool
+	compressThreshold  int
 }
 
 // SetMaxQueues 设置缓冲区的数量
 func SetMaxQueues(maxQueues int) Option {
 	return func(o *options) {
+		o.fixed("SetMaxQueues")
 		o.maxQueues = maxQueues
 	}
 }
@@ -46,28 +174,64 @@ func SetMaxQueues(maxQueues int) Option {
 // SetMaxWorkers 设置工作线程数
 func SetMaxWorkers(maxWorkers int) Option {
 	return func(o *options) {
+		o.fixed("SetMaxWorkers")
 		o.maxWorkers = maxWorkers
 	}
 }
 
-// SetExtra 设置扩展参数
+// SetExtra 设置扩展参数，error 类型的值保存为错误信息，通道、函数等无法写入数据库的值被忽略并通过内部日志报告
 func SetExtra(extra map[string]interface{}) Option {
 	return func(o *options) {
-		o.extra = extra
+		o.extra = o.normalizeExtra(extra)
+	}
+}
+
+// SetExtraForLevel 设置指定级别的扩展参数，在全局扩展参数之后合并，不覆盖已有字段
+func SetExtraForLevel(level logrus.Level, extra map[string]interface{}) Option {
+	return func(o *options) {
+		levelExtra := make(map[logrus.Level]map[string]interface{}, len(o.levelExtra)+1)
+		for l, e := range o.levelExtra {
+			levelExtra[l] = e
+		}
+		levelExtra[level] = o.normalizeExtra(extra)
+		o.levelExtra = levelExtra
 	}
 }
 
 // SetExec 设置Execer接口
 func SetExec(exec ExecCloser) Option {
 	return func(o *options) {
+		o.fixed("SetExec")
 		o.exec = exec
 	}
 }
 
-// SetFilter 设置条目过滤器
+// SetPreFilter 设置入队前的过滤器，在Fire中解析调用方与入队之前执行，返回nil时立即丢弃条目，
+// 不占用队列空间。过滤器收到的是记录日志时的原始条目，需要修改时应返回新的条目。
+// 过滤分为两个阶段：开销小的丢弃判断使用入队前过滤器，字段转换等使用在工作线程中执行的SetFilter/AddFilter
+func SetPreFilter(filter FilterHandle) Option {
+	return func(o *options) {
+		o.preFilter = filter
+	}
+}
+
+// SetFilter 设置条目过滤器，替换已添加的全部过滤器，filter为nil时清除全部过滤器
 func SetFilter(filter FilterHandle) Option {
 	return func(o *options) {
-		o.filter = filter
+		o.filters = nil
+		if filter != nil {
+			o.filters = []FilterHandle{filter}
+		}
+	}
+}
+
+// AddFilter 追加条目过滤器，按添加顺序执行，返回nil时丢弃条目，filter为nil时忽略
+func AddFilter(filter FilterHandle) Option {
+	return func(o *options) {
+		if filter == nil {
+			return
+		}
+		o.filters = append(o.filters[:len(o.filters):len(o.filters)], filter)
 	}
 }
 
@@ -81,13 +245,123 @@ func SetLevels(levels ...logrus.Level) Option {
 	}
 }
 
-// SetOut 设置错误输出
+// SetLevelStrings 以级别名称(如 "info"、"error")设置可用的日志级别，便于从配置文件读取。
+// 无效的名称通过内部日志输出警告后忽略，没有有效的名称时保持原有的级别
+func SetLevelStrings(names ...string) Option {
+	return func(o *options) {
+		var levels []logrus.Level
+		for _, name := range names {
+			level, err := logrus.ParseLevel(name)
+			if err != nil {
+				o.invalidLevels = append(o.invalidLevels[:len(o.invalidLevels):len(o.invalidLevels)], name)
+				continue
+			}
+			levels = append(levels, level)
+		}
+		SetLevels(levels...)(o)
+	}
+}
+
+// SetEnrichHost 设置是否为条目添加主机名(hostname)与进程号(pid)，不覆盖已有字段
+func SetEnrichHost(enrichHost bool) Option {
+	return func(o *options) {
+		o.enrichHost = enrichHost
+	}
+}
+
+// LatencyObserver 写入延迟观察者，参数为条目在队列中等待的时长(同步写入时为0，批量写入时取最长的)、
+// 单次Exec调用的耗时与结果
+type LatencyObserver func(wait, d time.Duration, err error)
+
+// SetLatencyObserver 设置写入延迟观察者，写入失败时同样会被调用
+func SetLatencyObserver(observer LatencyObserver) Option {
+	return func(o *options) {
+		o.latencyObserver = observer
+	}
+}
+
+// SetClock 设置钩子使用的时钟(默认为 time.Now)，用于耗时统计与条目缺少时间时的时间字段
+func SetClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.fixed("SetClock")
+		o.clock = clock
+	}
+}
+
+// ErrorHandle 写入失败的错误处理程序
+type ErrorHandle func(entry *logrus.Entry, err error)
+
+// SetErrorHandler 设置写入失败的错误处理程序，设置后不再将错误输出到out。
+// 处理程序中记录的日志不再进入钩子；同步写入时logrus在调用钩子期间持有Logger的锁，
+// 处理程序不能通过同一个Logger记录日志
+func SetErrorHandler(handler ErrorHandle) Option {
+	return func(o *options) {
+		o.errorHandler = handler
+	}
+}
+
+// SetRecoverWorker 设置是否恢复工作线程与同步写入中过滤器或Exec引发的panic(默认开启)
+func SetRecoverWorker(recoverWorker bool) Option {
+	return func(o *options) {
+		o.recoverWorker = recoverWorker
+	}
+}
+
+// ResultHandle 每次写入尝试后的回调，err为nil表示写入成功
+type ResultHandle func(entry *logrus.Entry, err error)
+
+// SetResultHook 设置每次写入尝试(包括重试)后的回调，成功与失败时都会调用，用于测试与监控
+func SetResultHook(handle ResultHandle) Option {
+	return func(o *options) {
+		o.resultHook = handle
+	}
+}
+
+// SetInternalLogger 设置钩子自身诊断信息(写入错误、配置警告等)的输出，设置后不再写入out。
+// 其中记录的日志不再进入钩子；同步写入时同样不能使用挂载了本钩子的Logger
+func SetInternalLogger(logger func(msg string)) Option {
+	return func(o *options) {
+		o.internalLogger = logger
+	}
+}
+
+// SetOut 设置错误输出，每条信息以一次写入输出完整的一行，并发写入时不会交错
 func SetOut(out io.Writer) Option {
 	return func(o *options) {
 		o.out = out
 	}
 }
 
+// SetSync 设置是否绕过队列，在Fire中同步写入条目
+func SetSync(sync bool) Option {
+	return func(o *options) {
+		o.sync = sync
+	}
+}
+
+// SetSyncLevels 设置同步写入的日志级别(如 FatalLevel、PanicLevel)，其余级别仍异步写入
+func SetSyncLevels(levels ...logrus.Level) Option {
+	return func(o *options) {
+		o.syncLevels = levels
+	}
+}
+
+// SetContextFields 设置从条目上下文中提取的值，键为上下文的键，值为保存的字段名称。
+// 上下文中不存在的键以及条目中已有的字段会被跳过
+func SetContextFields(fields map[interface{}]string) Option {
+	return func(o *options) {
+		o.contextFields = fields
+	}
+}
+
+// SetBaseContext 设置写入时使用的基础上下文，取消后将中止写入。
+// 默认Exec在上下文可以取消时通过驱动层集合写入，客户端未提供驱动层的数据库时写入无法被中止
+func SetBaseContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
 // Option 钩子参数选项
 type Option func(*options)
 
@@ -107,91 +381,727 @@ func DefaultWithURL(sess *mongodb.MongoDBClient, cName string, opts ...Option) *
 	return New(options...)
 }
 
-// New 创建一个要添加到logger实例的钩子
+// NewStrict 与New相同，未设置Exec时返回ErrNoExec，参数无效时返回对应的错误
+func NewStrict(opt ...Option) (*Hook, error) {
+	opts := defaultOptions
+	for _, o := range opt {
+		o(&opts)
+	}
+	if opts.exec == nil {
+		return nil, ErrNoExec
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return New(opt...), nil
+}
+
+// New 创建一个要添加到logger实例的钩子，未设置Exec时钩子将丢弃所有条目，
+// 无效的参数输出警告并使用默认值
 func New(opt ...Option) *Hook {
 	opts := defaultOptions
 	for _, o := range opt {
 		o(&opts)
 	}
+	return newHook(opts)
+}
+
+// newHook 使用已应用的参数创建钩子
+func newHook(opts options) *Hook {
+	opts.reportInvalid()
+	for err := opts.validate(); err != nil; err = opts.validate() {
+		opts.warn("Invalid options, using defaults: %s", err.Error())
+		opts.reset(err)
+	}
 
 	if opts.exec == nil {
 		// panic("Unknown Execer interface implementation")
-		logrus.Info("Unknown Execer interface implementation")
+		opts.warn("Unknown Execer interface implementation, entries will be discarded")
+	}
+
+	q := opts.queue
+	if q == nil && opts.maxScaleWorkers > 0 {
+		q = newScalingQueue(opts.maxQueues, opts.minWorkers, opts.maxScaleWorkers)
+	} else if q == nil {
+		q = newJobQueue(opts.maxQueues, opts.maxWorkers)
 	}
 
-	q := queue.NewQueue(opts.maxQueues, opts.maxWorkers)
-	q.Run()
+	hostName, err := os.Hostname()
+	if err != nil {
+		hostName = "unknown"
+	}
 
-	return &Hook{
-		opts: opts,
-		q:    q,
+	h := &Hook{
+		q:           q,
+		buf:         newBuffer(opts.maxQueues, opts.priorityLevels, opts.clock),
+		pending:     newPending(),
+		cardinality: newCardinalities(opts.cardinality),
+		hostname:    hostName,
+		pid:         os.Getpid(),
+	}
+	opts.reentry = &h.reentry
+	opts.fixedOptions = nil
+	h.cfg.Store(&opts)
+	if b, ok := opts.exec.(optionsBinder); ok {
+		b.bind(h.options)
+	}
+	if opts.sharedDead != nil {
+		h.dead = opts.sharedDead
+	} else if opts.deadLetter != nil {
+		h.dead = &deadLetter{
+			w:         opts.deadLetter,
+			serialize: opts.serializer,
+			framed:    opts.deserializer != nil,
+		}
+	}
+	if opts.breakerFailures > 0 {
+		h.breaker = newBreaker(opts.breakerFailures, opts.breakerCooldown)
+	}
+	if opts.dedupWindow > 0 {
+		h.dedup = newDeduper(opts.dedupWindow, opts.dedupKey, h.enqueueExpired)
+	}
+	if opts.batchSize > 1 {
+		h.batch = newBatcher(opts.batchSize, opts.flushInterval, h.execBatch, h.baseContext)
+	}
+	if opts.spillFile != "" {
+		if h.spill, err = openSpill(opts.spillFile, opts.serializer, opts.deserialize()); err != nil {
+			h.warn("Spill file error: %s", err.Error())
+		}
+		h.replaySpill()
 	}
+	if opts.autoStart {
+		h.Start()
+	}
+	return h
 }
 
 // Hook 将日志发送到 mongo 数据库
 type Hook struct {
-	opts options
-	q    *queue.Queue
+	stats   counters
+	cfg     atomic.Value // *options
+	q       JobQueue
+	buf     *buffer
+	pending *pending
+	batch   *batcher
+	dead    *deadLetter
+	spill   *spill
+
+	breaker     *breaker
+	dedup       *deduper
+	cardinality map[string]*cardinality
+
+	hostname string
+	pid      int
+
+	cfgLock   sync.Mutex
+	aboveHigh int32
+	reentry   reentry
+
+	signalLock sync.Mutex
+	signalStop func()
+
+	errLock sync.RWMutex
+	errs    errChan
+
+	lock          sync.RWMutex
+	closed        bool
+	terminated    bool
+	closeOnce     sync.Once
+	terminateOnce sync.Once
+	startOnce     sync.Once
+	started       int32
+	closeErr      error
 }
 
 // Levels 返回可用的日志记录级别
 func (h *Hook) Levels() []logrus.Level {
-	return h.opts.levels
+	return h.options().levels
+}
+
+// SetLevelsRuntime 在运行时调整可用的日志级别。logrus 只在 AddHook 时读取 Levels，
+// 因此新增的级别需包含在添加钩子时的级别中才会生效
+func (h *Hook) SetLevelsRuntime(levels ...logrus.Level) {
+	if len(levels) == 0 {
+		return
+	}
+	h.Reconfigure(SetLevels(append([]logrus.Level(nil), levels...)...))
 }
 
 // Fire 触发日志事件时将调用
 func (h *Hook) Fire(entry *logrus.Entry) error {
-	if entry.HasCaller() {
-		funcVal := entry.Caller.Function
-		fileVal := fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
-		entry.Data["func"] = funcVal
-		entry.Data["file"] = fileVal
+	if entry == nil {
+		return nil
 	}
+	return h.fire(entry.Context, entry)
+}
 
-	hostName, err := os.Hostname()
-	if err != nil {
-		hostName = "unknown"
+// FireContext 与Fire相同，ctx 随条目保存在 entry.Context 中，
+// 写入时的取消与超时仍由基础上下文控制
+func (h *Hook) FireContext(ctx context.Context, entry *logrus.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return h.fire(ctx, entry)
+}
+
+func (h *Hook) fire(ctx context.Context, entry *logrus.Entry) error {
+	o := h.options()
+	if entry == nil || o.exec == nil {
+		return nil
+	}
+	// 错误处理程序与内部日志中产生的日志不再进入钩子，避免循环写入
+	if h.reentry.active() {
+		return nil
+	}
+	if !containsLevel(h.Levels(), entry.Level) {
+		return nil
+	}
+	audit := isAudit(o, entry)
+	if !audit && !h.sample(entry) {
+		atomic.AddUint64(&h.stats.sampled, 1)
+		h.drop(entry, DropSampled)
+		return nil
+	}
+	if filter := o.preFilter; filter != nil {
+		in := entry
+		if entry = filter(entry); entry == nil {
+			h.drop(in, DropFiltered)
+			return nil
+		}
 	}
 
-	entry.Data["hostname"] = hostName
+	caller := h.caller(entry)
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if h.closed || h.terminated {
+		// 队列已停止，设置了死信时条目经过过滤与脱敏后写入死信
+		if h.dead != nil {
+			orig := h.copyEntry(entry)
+			if dead := h.prepare(orig); dead != nil {
+				if err := h.dead.write([]*logrus.Entry{dead}); err != nil {
+					h.warn("Dead letter error: %s", err.Error())
+				}
+				if dead != orig {
+					h.release(dead)
+				}
+			}
+			h.release(orig)
+		} else {
+			h.drop(entry, DropClosed)
+		}
+		return ErrClosed
+	}
 
+	// 注入的字段只写入复制后的条目，与用户字段同名时加上保留前缀
 	entry = h.copyEntry(entry)
-	h.q.Push(queue.NewJob(entry, func(v interface{}) {
-		h.exec(v.(*logrus.Entry))
-	}))
+	entry.Context = ctx
+	entry.Caller = caller
+	prefix := o.reservedPrefix
+	if caller != nil {
+		reserve(entry.Data, prefix, "func", caller.Function)
+		reserve(entry.Data, prefix, "file", callerFile(caller))
+	}
+	reserve(entry.Data, prefix, "hostname", h.hostname)
+	if o.generateEventID {
+		reserve(entry.Data, prefix, o.eventIDField, newEventID())
+	}
+	h.contextFields(ctx, entry)
+	if o.includeGoroutineID {
+		reserve(entry.Data, prefix, "goid", goroutineID())
+	}
+	if containsLevel(o.stackLevels, entry.Level) {
+		reserve(entry.Data, prefix, "stack", stackTrace(o.stackDepth))
+	}
+	if h.isSync(entry.Level) {
+		h.writeSync(entry)
+		return nil
+	}
+
+	if h.dedup != nil && !audit {
+		if entry = h.dedupe(entry); entry == nil {
+			return nil
+		}
+	}
+	return h.enqueue(entry)
+}
+
+// enqueue 按溢出策略将条目放入缓冲区，启动前队列已满时不阻塞而是返回ErrNotStarted
+func (h *Hook) enqueue(entry *logrus.Entry) error {
+	if h.spill != nil {
+		h.enqueueSpill(entry)
+		return nil
+	}
+
+	h.stamp(entry)
+	policy := h.options().overflow
+	if policy == Block && !h.isStarted() {
+		policy = DropNewest
+	}
+	stored, added, dropped := h.buf.put(entry, policy)
+	if dropped != nil {
+		atomic.AddUint64(&h.stats.dropped, 1)
+		h.drop(dropped, DropOverflow)
+	}
+	if stored {
+		atomic.AddUint64(&h.stats.enqueued, 1)
+	}
+	h.checkWaterMarks()
+	if added {
+		h.push(entry.Level)
+	}
+	if !stored && policy != h.options().overflow {
+		return ErrNotStarted
+	}
 	return nil
 }
 
+// push 推送一个任务，每个任务从缓冲区取出一个条目，被淘汰的条目不再占用任务
+func (h *Hook) push(level logrus.Level) {
+	h.q.Push(&writeJob{hook: h, level: level, seq: h.pending.push()})
+}
+
+// runJob 执行一个任务，默认队列按缓冲区的优先级取出条目，自定义队列取出该级别最早的条目
+func (h *Hook) runJob(level logrus.Level) {
+	var entry *logrus.Entry
+	if h.options().queue == nil {
+		entry = h.buf.pop()
+	} else {
+		entry = h.buf.popLevel(level)
+	}
+	if entry == nil {
+		return
+	}
+	h.checkWaterMarks()
+	h.replaySpill()
+	atomic.AddInt64(&h.stats.active, 1)
+	defer atomic.AddInt64(&h.stats.active, -1)
+	defer h.recoverWorker(entry)
+	h.exec(h.options().ctx, entry)
+}
+
+// writeSync 在Fire中同步写入条目，过滤器或Exec引发的panic与工作线程中一样被恢复
+func (h *Hook) writeSync(entry *logrus.Entry) {
+	defer h.recoverWorker(entry)
+	orig := entry
+	if entry = h.prepare(entry); entry != nil {
+		h.write(h.options().ctx, entry)
+		h.release(entry)
+	}
+	if entry != orig {
+		h.release(orig)
+	}
+}
+
+// recoverWorker 恢复过滤器或Exec引发的panic，交给错误处理程序(未设置时输出到out)，保持工作线程继续运行
+func (h *Hook) recoverWorker(entries ...*logrus.Entry) {
+	if !h.options().recoverWorker {
+		return
+	}
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	err := fmt.Errorf("panic: %v", r)
+	if !h.options().handleError(err, entries...) {
+		h.warn("Recovered %s", err.Error())
+	}
+}
+
+func (h *Hook) warn(format string, args ...interface{}) {
+	h.options().warn(format, args...)
+}
+
+// outLock 串行写入out，多个工作线程同时出错时每条信息仍是完整的一行
+var outLock sync.Mutex
+
+// warn 输出钩子自身的诊断信息，设置了内部日志时交给内部日志，否则写入out
+func (o *options) warn(format string, args ...interface{}) {
+	msg := fmt.Sprintf("[Mongo-Hook] "+format, args...)
+	if o.internalLogger != nil {
+		defer o.reentry.enter()()
+		o.internalLogger(msg)
+		return
+	}
+	if o.out == nil {
+		return
+	}
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	outLock.Lock()
+	defer outLock.Unlock()
+	io.WriteString(o.out, msg)
+}
+
+// isSync 判断该级别的条目是否同步写入
+func (h *Hook) isSync(level logrus.Level) bool {
+	return h.options().sync || containsLevel(h.options().syncLevels, level)
+}
+
+func containsLevel(levels []logrus.Level, level logrus.Level) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
+
+// caller 返回条目的调用方，设置了callerSkip时重新遍历调用栈
+func (h *Hook) caller(entry *logrus.Entry) *runtime.Frame {
+	o := h.options()
+	if !o.resolveCaller || !entry.HasCaller() {
+		return nil
+	}
+	if o.callerSkip <= 0 && len(o.callerIgnore) == 0 {
+		return entry.Caller
+	}
+	if frame := callerFrame(o.callerSkip, o.callerIgnore); frame != nil {
+		return frame
+	}
+	return entry.Caller
+}
+
+// copyEntry 复制条目，条目没有Logger时使用标准Logger
 func (h *Hook) copyEntry(e *logrus.Entry) *logrus.Entry {
-	entry := logrus.NewEntry(e.Logger)
-	entry.Data = make(logrus.Fields)
+	logger := e.Logger
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	entry := h.newEntry(logger)
 	entry.Time = e.Time
 	entry.Level = e.Level
 	entry.Message = e.Message
+	entry.Caller = e.Caller
+	entry.Buffer = e.Buffer
+	entry.Context = e.Context
 	for k, v := range e.Data {
 		entry.Data[k] = v
 	}
 	return entry
 }
 
-func (h *Hook) exec(entry *logrus.Entry) {
-	if extra := h.opts.extra; extra != nil {
-		for k, v := range extra {
-			if _, ok := entry.Data[k]; !ok {
-				entry.Data[k] = v
+// exec 使用ctx写入条目，启用批量写入时加入批次
+func (h *Hook) exec(ctx context.Context, entry *logrus.Entry) {
+	h.dequeued(entry)
+	orig := entry
+	if entry = h.prepare(entry); entry == nil {
+		h.release(orig)
+		return
+	}
+	// 过滤器返回了新的条目时原条目不再使用
+	if entry != orig {
+		h.release(orig)
+	}
+	if h.batch != nil {
+		h.batch.add(ctx, entry)
+		return
+	}
+	h.write(ctx, entry)
+	h.release(entry)
+}
+
+// prepare 合并扩展参数、执行过滤器并脱敏，条目被过滤器丢弃时返回nil
+func (h *Hook) prepare(entry *logrus.Entry) *logrus.Entry {
+	mergeExtra(entry, h.options().extra)
+	mergeExtra(entry, h.options().levelExtra[entry.Level])
+	h.extractTrace(entry)
+	if h.options().enrichHost {
+		if _, ok := entry.Data["hostname"]; !ok {
+			entry.Data["hostname"] = h.hostname
+		}
+		if _, ok := entry.Data["pid"]; !ok {
+			entry.Data["pid"] = h.pid
+		}
+	}
+	for _, filter := range h.options().filters {
+		in := entry
+		if entry = filter(entry); entry == nil {
+			h.drop(in, DropFiltered)
+			return nil
+		}
+	}
+	h.limitCardinality(entry)
+	h.redact(entry)
+	h.selectFields(entry)
+	if !h.limit(entry) {
+		atomic.AddUint64(&h.stats.dropped, 1)
+		h.drop(entry, DropSizeLimit)
+		return nil
+	}
+	return entry
+}
+
+// contextFields 将上下文中的值写入复制后的条目
+func (h *Hook) contextFields(ctx context.Context, entry *logrus.Entry) {
+	if ctx == nil {
+		return
+	}
+	for key, field := range h.options().contextFields {
+		if _, ok := entry.Data[field]; ok {
+			continue
+		}
+		if v := ctx.Value(key); v != nil {
+			entry.Data[field] = v
+		}
+	}
+}
+
+// mergeExtra 合并扩展参数，不覆盖已有字段
+func mergeExtra(entry *logrus.Entry, extra map[string]interface{}) {
+	for k, v := range extra {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+}
+
+// write 写入单个条目，失败时按重试设置重试
+func (h *Hook) write(ctx context.Context, entry *logrus.Entry) {
+	attempts, err := h.retry(ctx, func() error {
+		return h.execEntry(ctx, entry)
+	}, entry)
+	h.report(attempts, err, entry)
+}
+
+// execEntry 使用ctx写入条目，Exec实现了ContextExecer时上下文取消可中止写入
+func (h *Hook) execEntry(ctx context.Context, entry *logrus.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	exec := h.options().exec
+	ce, ok := exec.(ContextExecer)
+	err := h.call(queueWait(entry), func() error {
+		return h.withTimeout(ctx, ok && cancellable(exec, false), func(ctx context.Context) error {
+			if ok {
+				return ce.ExecContext(ctx, entry)
+			}
+			return exec.Exec(entry)
+		})
+	})
+	h.result(err, entry)
+	return err
+}
+
+// call 调用一次Exec，统计耗时并更新熔断器状态
+func (h *Hook) call(wait time.Duration, fn func() error) error {
+	o := h.options()
+	if h.breaker != nil && !h.breaker.allow(o.clock()) {
+		return ErrCircuitOpen
+	}
+	finished := false
+	if h.breaker != nil {
+		defer func() {
+			// 写入引发panic时计为失败，避免试探写入后熔断器一直停留在半开状态
+			if !finished {
+				h.breaker.done(o.clock(), errExecPanic)
+			}
+		}()
+	}
+	start := o.clock()
+	err := fn()
+	finished = true
+	if h.breaker != nil {
+		h.breaker.done(o.clock(), err)
+	}
+	h.observe(wait, start, err)
+	return err
+}
+
+// observe 统计写入耗时与结果并传给延迟观察者
+func (h *Hook) observe(wait time.Duration, start time.Time, err error) {
+	d := h.options().clock().Sub(start)
+	atomic.AddUint64(&h.stats.execs, 1)
+	atomic.AddUint64(&h.stats.nanos, uint64(d))
+	if err != nil {
+		atomic.AddUint64(&h.stats.errors, 1)
+	}
+	if observer := h.options().latencyObserver; observer != nil {
+		observer(wait, d, err)
+	}
+}
+
+// execBatch 批量写入条目，Exec未实现BatchExecer或BatchContextExecer时逐条写入
+func (h *Hook) execBatch(ctx context.Context, entries []*logrus.Entry) {
+	defer h.release(entries...)
+	defer h.recoverWorker(entries...)
+	if batchExec, cancellable := batchWriter(h.options().exec); batchExec != nil {
+		pending := entries
+		attempts, err := h.retry(ctx, func() error {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
+			batch := pending
+			err := h.call(queueWait(batch...), func() error {
+				return h.withTimeout(ctx, cancellable, func(ctx context.Context) error {
+					return batchExec(ctx, batch)
+				})
+			})
+			// 部分失败时成功的条目不再重试
+			var batchErr *BatchError
+			if errors.As(err, &batchErr) {
+				var failed []*logrus.Entry
+				for i, entry := range batch {
+					if containsIndex(batchErr.Indices, i) {
+						failed = append(failed, entry)
+						h.result(err, entry)
+					} else {
+						h.result(nil, entry)
+						h.report(1, nil, entry)
+					}
+				}
+				if pending = failed; len(pending) == 0 {
+					return nil
+				}
+				return err
+			}
+			h.result(err, batch...)
+			return err
+		}, entries...)
+		h.report(attempts, err, pending...)
+		return
+	}
+	for _, entry := range entries {
+		entry := entry
+		attempts, err := h.retry(ctx, func() error {
+			return h.execEntry(ctx, entry)
+		}, entry)
+		h.report(attempts, err, entry)
+	}
+}
+
+// batchWriter 返回Exec的批量写入方法，abortable 表示实现了BatchContextExecer且上下文取消可中止写入。
+// 未实现批量写入时返回nil
+func batchWriter(exec ExecCloser) (write func(context.Context, []*logrus.Entry) error, abortable bool) {
+	if be, ok := exec.(BatchContextExecer); ok {
+		return be.BatchExecContext, cancellable(exec, true)
+	}
+	if be, ok := exec.(BatchExecer); ok {
+		return func(_ context.Context, entries []*logrus.Entry) error {
+			return be.BatchExec(entries)
+		}, false
+	}
+	return nil, false
+}
+
+// result 调用写入结果回调
+func (h *Hook) result(err error, entries ...*logrus.Entry) {
+	if handle := h.options().resultHook; handle != nil {
+		for _, entry := range entries {
+			handle(entry, err)
 		}
 	}
-	if filter := h.opts.filter; filter != nil {
-		entry = filter(entry)
+}
+
+// report 统计写入结果，写入失败时交给错误处理程序(未设置时输出到out)并将条目写入死信
+func (h *Hook) report(attempts int, err error, entries ...*logrus.Entry) {
+	if err == nil {
+		atomic.AddUint64(&h.stats.written, uint64(len(entries)))
+		return
+	}
+	atomic.AddUint64(&h.stats.failed, uint64(len(entries)))
+	h.sendError(err)
+	if !h.options().handleError(err, entries...) {
+		if attempts > 1 {
+			h.warn("Execution error after %d attempts: %s", attempts, err.Error())
+		} else {
+			h.warn("Execution error: %s", err.Error())
+		}
 	}
-	err := h.opts.exec.Exec(entry)
-	if err != nil && h.opts.out != nil {
-		fmt.Fprintf(h.opts.out, "[Mongo-Hook] Execution error: %s", err.Error())
+	if h.dead != nil {
+		if dead := h.deadLetters(entries); len(dead) > 0 {
+			if err := h.dead.write(dead); err != nil {
+				h.warn("Dead letter error: %s", err.Error())
+			}
+		}
 	}
 }
 
-// Flush 等待日志队列为空
+// Flush 等待日志队列为空并停止工作线程，重复调用是安全的；之后Fire返回ErrClosed
 func (h *Hook) Flush() {
+	h.FlushTimeout(0)
+}
+
+// FlushTimeout 最多等待d使日志队列为空，超时返回ErrFlushTimeout，d为0时一直等待
+func (h *Hook) FlushTimeout(d time.Duration) error {
+	if d <= 0 {
+		h.terminate()
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.terminate()
+		close(done)
+	}()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return ErrFlushTimeout
+	}
+}
+
+// FlushReport 等待日志队列为空，返回等待期间写入成功与失败的条目数
+func (h *Hook) FlushReport() (written, failed int) {
+	w := atomic.LoadUint64(&h.stats.written)
+	f := atomic.LoadUint64(&h.stats.failed)
+	h.Flush()
+	return int(atomic.LoadUint64(&h.stats.written) - w), int(atomic.LoadUint64(&h.stats.failed) - f)
+}
+
+// terminate 停止工作线程并写入剩余的批量条目，溢出文件中的条目保留到下次启动时重放。
+// 重复调用时等待第一次调用完成
+func (h *Hook) terminate() {
+	h.terminateOnce.Do(h.doTerminate)
+}
+
+func (h *Hook) doTerminate() {
+	h.Start()
+	if h.dedup != nil {
+		if entry := h.dedup.flush(); entry != nil {
+			h.enqueue(entry)
+		}
+	}
+
+	h.lock.Lock()
+	h.terminated = true
+	h.lock.Unlock()
+
 	h.q.Terminate()
+	if h.batch != nil {
+		h.batch.close()
+	}
+}
+
+// Ping 检查Exec对应的存储是否可用
+func (h *Hook) Ping(ctx context.Context) error {
+	if h.options().exec == nil {
+		return ErrNoExec
+	}
+	return h.options().exec.Ping(ctx)
+}
+
+// Close 等待日志队列为空后关闭Exec，重复调用是安全的
+func (h *Hook) Close() error {
+	h.closeOnce.Do(func() {
+		h.lock.Lock()
+		h.closed = true
+		h.lock.Unlock()
+
+		h.Flush()
+		if h.spill != nil {
+			h.spill.close()
+		}
+		if h.options().exec != nil {
+			h.closeErr = h.options().exec.Close()
+		}
+		h.closeErrors()
+	})
+	return h.closeErr
 }
@@ -0,0 +1,594 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// gateExec 在gate关闭前阻塞写入，每次开始写入时向started发送条目
+type gateExec struct {
+	*MemoryExec
+	gate    chan struct{}
+	started chan *logrus.Entry
+}
+
+func newGateExec() *gateExec {
+	return &gateExec{
+		MemoryExec: NewMemoryExec(),
+		gate:       make(chan struct{}),
+		started:    make(chan *logrus.Entry, 100),
+	}
+}
+
+func (e *gateExec) Exec(entry *logrus.Entry) error {
+	e.started <- entry
+	<-e.gate
+	return e.MemoryExec.Exec(entry)
+}
+
+// testEntry 创建一个带Logger的条目
+func testEntry(level logrus.Level, msg string) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.New())
+	entry.Level = level
+	entry.Message = msg
+	entry.Time = time.Now()
+	return entry
+}
+
+// messages 返回条目的消息
+func messages(entries []*logrus.Entry) []string {
+	msgs := make([]string, len(entries))
+	for i, entry := range entries {
+		msgs[i] = entry.Message
+	}
+	return msgs
+}
+
+func assertMessages(t *testing.T, entries []*logrus.Entry, want ...string) {
+	t.Helper()
+	if got := messages(entries); !reflect.DeepEqual(got, want) {
+		t.Fatalf("messages = %q, want %q", got, want)
+	}
+}
+
+// ctxExec 阻塞到上下文取消后返回上下文的错误
+type ctxExec struct {
+	*MemoryExec
+	started chan struct{}
+}
+
+func newCtxExec() *ctxExec {
+	return &ctxExec{MemoryExec: NewMemoryExec(), started: make(chan struct{}, 100)}
+}
+
+func (e *ctxExec) ExecContext(ctx context.Context, entry *logrus.Entry) error {
+	e.started <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (e *ctxExec) BatchExecContext(ctx context.Context, entries []*logrus.Entry) error {
+	e.started <- struct{}{}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBaseContextCancelsExec(t *testing.T) {
+	for _, batchSize := range []int{0, 2} {
+		ctx, cancel := context.WithCancel(context.Background())
+		exec := newCtxExec()
+		errs := make(chan error, 10)
+		h := New(SetExec(exec), SetBaseContext(ctx), SetBatchSize(batchSize),
+			SetErrorHandler(func(entry *logrus.Entry, err error) { errs <- err }))
+		h.Fire(testEntry(logrus.InfoLevel, "a"))
+		h.Fire(testEntry(logrus.InfoLevel, "b"))
+		<-exec.started
+		cancel()
+
+		if err := h.FlushTimeout(time.Second); err != nil {
+			t.Fatalf("batch size %d: %v", batchSize, err)
+		}
+		if err := <-errs; err != context.Canceled {
+			t.Fatalf("batch size %d: error = %v, want context.Canceled", batchSize, err)
+		}
+	}
+}
+
+func TestCopyEntryKeepsCallerAndBuffer(t *testing.T) {
+	h := New(SetExec(NewMemoryExec()))
+	defer h.Close()
+
+	entry := testEntry(logrus.InfoLevel, "a")
+	entry.Logger.SetReportCaller(true)
+	entry.Caller = &runtime.Frame{Function: "main.main", File: "main.go", Line: 1}
+	entry.Buffer = new(bytes.Buffer)
+
+	c := h.copyEntry(entry)
+	if !c.HasCaller() || c.Caller != entry.Caller {
+		t.Fatalf("caller = %v, want %v", c.Caller, entry.Caller)
+	}
+	if c.Buffer != entry.Buffer {
+		t.Fatal("buffer was not copied")
+	}
+}
+
+func TestExecEntryHasCaller(t *testing.T) {
+	exec := NewMemoryExec()
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	log.SetReportCaller(true)
+	log.AddHook(New(SetExec(exec), SetSync(true)))
+
+	log.Info("a")
+	entries := exec.Entries()
+	if len(entries) != 1 || !entries[0].HasCaller() {
+		t.Fatalf("entries = %v, want one entry with caller", entries)
+	}
+}
+
+func TestSyncWritesBeforeReturn(t *testing.T) {
+	exec := NewMemoryExec()
+	h := New(SetExec(exec), SetSync(true))
+	defer h.Close()
+
+	for i, msg := range []string{"a", "b", "c"} {
+		h.Fire(testEntry(logrus.InfoLevel, msg))
+		if n := len(exec.Entries()); n != i+1 {
+			t.Fatalf("after Fire(%s): %d entries written, want %d", msg, n, i+1)
+		}
+	}
+}
+
+func TestSyncLevels(t *testing.T) {
+	exec := newGateExec()
+	h := New(SetExec(exec), SetSyncLevels(logrus.ErrorLevel))
+
+	// 异步级别的条目不等待写入
+	h.Fire(testEntry(logrus.InfoLevel, "info"))
+	<-exec.started
+
+	done := make(chan struct{})
+	go func() {
+		h.Fire(testEntry(logrus.ErrorLevel, "error"))
+		close(done)
+	}()
+	<-exec.started
+	select {
+	case <-done:
+		t.Fatal("Fire returned before the synchronous write finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(exec.gate)
+	<-done
+	found := false
+	for _, entry := range exec.Entries() {
+		found = found || entry.Message == "error"
+	}
+	if !found {
+		t.Fatal("synchronous entry was not written when Fire returned")
+	}
+	h.Flush()
+}
+
+func TestNilFilter(t *testing.T) {
+	upper := func(entry *logrus.Entry) *logrus.Entry {
+		entry.Message = strings.ToUpper(entry.Message)
+		return entry
+	}
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{[]Option{SetFilter(nil)}, "a"},
+		{[]Option{SetFilter(upper), SetFilter(nil)}, "a"},
+		{[]Option{AddFilter(nil), AddFilter(upper), AddFilter(nil)}, "A"},
+	}
+	for i, tt := range tests {
+		exec := NewMemoryExec()
+		h := New(append(tt.opts, SetExec(exec), SetSync(true), SetRecoverWorker(false))...)
+		h.Fire(testEntry(logrus.InfoLevel, "a"))
+		assertMessages(t, exec.Entries(), tt.want)
+		if len(h.options().filters) > 1 {
+			t.Fatalf("case %d: %d filters", i, len(h.options().filters))
+		}
+	}
+}
+
+func TestSetLevelsRuntimeConcurrent(t *testing.T) {
+	exec := NewMemoryExec()
+	h := New(SetExec(exec))
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					h.Fire(testEntry(logrus.DebugLevel, "debug"))
+					h.Levels()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		if i%2 == 0 {
+			h.SetLevelsRuntime(logrus.ErrorLevel)
+		} else {
+			h.SetLevelsRuntime(logrus.ErrorLevel, logrus.DebugLevel)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	h.SetLevelsRuntime(logrus.ErrorLevel)
+	h.Drain()
+	exec.Reset()
+	h.Fire(testEntry(logrus.DebugLevel, "debug"))
+	h.Fire(testEntry(logrus.ErrorLevel, "error"))
+	h.Flush()
+	assertMessages(t, exec.Entries(), "error")
+}
+
+// funcExec 由函数实现的Exec
+type funcExec func(*logrus.Entry) error
+
+func (f funcExec) Exec(entry *logrus.Entry) error { return f(entry) }
+func (f funcExec) Ping(ctx context.Context) error { return nil }
+func (f funcExec) Close() error                   { return nil }
+
+func TestPanicLevel(t *testing.T) {
+	exec := NewMemoryExec()
+	h := New(SetExec(exec), SetMaxWorkers(1))
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	log.SetLevel(logrus.TraceLevel)
+	log.AddHook(h)
+
+	func() {
+		defer func() {
+			r := recover()
+			if entry, ok := r.(*logrus.Entry); !ok || entry.Message != "panic" {
+				t.Fatalf("recovered %v, want the panic entry", r)
+			}
+		}()
+		log.Trace("trace")
+		log.Panic("panic")
+	}()
+	h.Flush()
+
+	entries := exec.Entries()
+	assertMessages(t, entries, "trace", "panic")
+	if entries[1].Level != logrus.PanicLevel {
+		t.Fatalf("level = %s, want panic", entries[1].Level)
+	}
+}
+
+func TestRecoverFilterPanic(t *testing.T) {
+	panicky := func(entry *logrus.Entry) *logrus.Entry {
+		if entry.Message == "panic" {
+			panic("filter panicked")
+		}
+		return entry
+	}
+	for _, sync := range []bool{false, true} {
+		exec := NewMemoryExec()
+		var errs []error
+		h := New(SetExec(exec), SetMaxWorkers(1), SetSync(sync), SetFilter(panicky),
+			SetErrorHandler(func(entry *logrus.Entry, err error) { errs = append(errs, err) }))
+		for _, msg := range []string{"a", "panic", "b", "panic", "c"} {
+			h.Fire(testEntry(logrus.InfoLevel, msg))
+		}
+		h.Flush()
+
+		assertMessages(t, exec.Entries(), "a", "b", "c")
+		if len(errs) != 2 || !strings.Contains(errs[0].Error(), "filter panicked") {
+			t.Fatalf("sync %v: errors = %v", sync, errs)
+		}
+	}
+}
+
+func TestDrainReturnsUnderContinuousLogging(t *testing.T) {
+	exec := NewMemoryExec()
+	slow := funcExec(func(entry *logrus.Entry) error {
+		time.Sleep(200 * time.Microsecond)
+		return exec.Exec(entry)
+	})
+	h := New(SetExec(slow), SetMaxQueues(64), SetMaxWorkers(2))
+	defer h.Flush()
+	for i := 0; i < 50; i++ {
+		h.Fire(testEntry(logrus.InfoLevel, "before"))
+	}
+
+	// 记录速度超过写入速度，队列始终不为空
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					h.Fire(testEntry(logrus.InfoLevel, "after"))
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		h.Drain()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Drain did not return while logging continued")
+	}
+	n := 0
+	for _, entry := range exec.Entries() {
+		if entry.Message == "before" {
+			n++
+		}
+	}
+	if n != 50 {
+		t.Fatalf("%d entries logged before Drain were written, want 50", n)
+	}
+}
+
+func TestFireAfterCloseDeadLetter(t *testing.T) {
+	var dead bytes.Buffer
+	drop := func(entry *logrus.Entry) *logrus.Entry {
+		if entry.Message == "filtered" {
+			return nil
+		}
+		return entry
+	}
+	h := New(SetExec(NewMemoryExec()), SetDeadLetter(&dead), SetRedactKeys("password"),
+		SetExcludeFields("secret"), SetFilter(drop))
+	h.Close()
+
+	entry := testEntry(logrus.ErrorLevel, "late")
+	entry.Data["password"] = "hunter2"
+	entry.Data["secret"] = "s3cr3t"
+	if err := h.Fire(entry); err != ErrClosed {
+		t.Fatalf("Fire after Close returned %v, want ErrClosed", err)
+	}
+	if err := h.Fire(testEntry(logrus.ErrorLevel, "filtered")); err != ErrClosed {
+		t.Fatalf("Fire after Close returned %v, want ErrClosed", err)
+	}
+
+	// 死信中的条目同样经过过滤、脱敏与字段选择
+	lines := strings.Split(strings.TrimSpace(dead.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("dead letter = %q, want one entry", dead.String())
+	}
+	got, err := unmarshalEntry([]byte(lines[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Message != "late" || got.Data["password"] != "[REDACTED]" {
+		t.Fatalf("dead letter entry = %q %v", got.Message, got.Data)
+	}
+	if _, ok := got.Data["secret"]; ok {
+		t.Fatalf("excluded field was written to the dead letter: %v", got.Data)
+	}
+	if entry.Data["password"] != "hunter2" {
+		t.Fatal("redaction changed the caller's entry")
+	}
+}
+
+func TestDoubleFlush(t *testing.T) {
+	exec := NewMemoryExec()
+	h := New(SetExec(exec))
+	h.Fire(testEntry(logrus.InfoLevel, "a"))
+	h.Flush()
+
+	done := make(chan struct{})
+	go func() {
+		h.Flush()
+		if err := h.FlushTimeout(time.Second); err != nil {
+			t.Errorf("FlushTimeout after Flush returned %v", err)
+		}
+		h.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second Flush did not return")
+	}
+	if err := h.Fire(testEntry(logrus.InfoLevel, "b")); err != ErrClosed {
+		t.Fatalf("Fire after Flush returned %v, want ErrClosed", err)
+	}
+	assertMessages(t, exec.Entries(), "a")
+}
+
+func TestLogFromErrorHandler(t *testing.T) {
+	tests := []struct {
+		sync      bool
+		batchSize int
+	}{
+		{false, 0},
+		{true, 0},
+		{false, 2},
+	}
+	for _, tt := range tests {
+		var lock sync.Mutex
+		var written []string
+		exec := funcExec(func(entry *logrus.Entry) error {
+			lock.Lock()
+			written = append(written, entry.Message)
+			lock.Unlock()
+			return errors.New("write failed")
+		})
+		log := logrus.New()
+		log.SetOutput(ioutil.Discard)
+		// 同步写入时logrus持有Logger的锁，处理程序通过另一个使用同一钩子的Logger记录日志
+		handlerLog := log
+		if tt.sync {
+			handlerLog = logrus.New()
+			handlerLog.SetOutput(ioutil.Discard)
+		}
+		var handled int32
+		h := New(SetExec(exec), SetSync(tt.sync), SetBatchSize(tt.batchSize),
+			SetErrorHandler(func(entry *logrus.Entry, err error) {
+				atomic.AddInt32(&handled, 1)
+				// 错误处理程序中记录的日志不再进入钩子，不会再次失败并递归调用错误处理程序
+				handlerLog.WithError(err).Error("handler")
+			}))
+		log.AddHook(h)
+		if tt.sync {
+			handlerLog.AddHook(h)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			log.Error("a")
+			log.Error("b")
+			h.Flush()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("sync %v, batch size %d: logging from the error handler did not return", tt.sync, tt.batchSize)
+		}
+		if n := atomic.LoadInt32(&handled); n != 2 {
+			t.Fatalf("sync %v, batch size %d: error handler called %d times, want 2", tt.sync, tt.batchSize, n)
+		}
+		for _, msg := range written {
+			if msg == "handler" {
+				t.Fatalf("sync %v, batch size %d: entry logged by the error handler was written", tt.sync, tt.batchSize)
+			}
+		}
+	}
+}
+
+func TestLogFromInternalLogger(t *testing.T) {
+	var lock sync.Mutex
+	var written []string
+	exec := funcExec(func(entry *logrus.Entry) error {
+		lock.Lock()
+		written = append(written, entry.Message)
+		lock.Unlock()
+		return errors.New("write failed")
+	})
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	h := New(SetExec(exec), SetInternalLogger(func(msg string) { log.Error(msg) }))
+	log.AddHook(h)
+
+	log.Error("a")
+	h.Flush()
+
+	// 内部日志记录的诊断信息不再进入钩子
+	lock.Lock()
+	defer lock.Unlock()
+	if len(written) != 1 || written[0] != "a" {
+		t.Fatalf("written = %q, want [a]", written)
+	}
+}
+
+// lineWriter 记录每次写入，检查写入是否并发
+type lineWriter struct {
+	active     int32
+	concurrent int32
+	lock       sync.Mutex
+	writes     []string
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if atomic.AddInt32(&w.active, 1) > 1 {
+		atomic.StoreInt32(&w.concurrent, 1)
+	}
+	defer atomic.AddInt32(&w.active, -1)
+	// 放大并发写入交错的窗口
+	runtime.Gosched()
+	w.lock.Lock()
+	w.writes = append(w.writes, string(p))
+	w.lock.Unlock()
+	return len(p), nil
+}
+
+func TestOutLinesConcurrent(t *testing.T) {
+	out := &lineWriter{}
+	failing := funcExec(func(entry *logrus.Entry) error {
+		return errors.New("write failed")
+	})
+	h := New(SetExec(failing), SetMaxWorkers(4), SetOut(out))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				h.Fire(testEntry(logrus.InfoLevel, "a"))
+				h.warn("warning %d", j)
+			}
+		}()
+	}
+	wg.Wait()
+	h.Flush()
+
+	if atomic.LoadInt32(&out.concurrent) != 0 {
+		t.Fatal("out was written concurrently")
+	}
+	if len(out.writes) < 400 {
+		t.Fatalf("%d writes to out, want at least 400", len(out.writes))
+	}
+	// 每次写入都是以前缀开头、以换行结尾的完整信息
+	for _, line := range out.writes {
+		if !strings.HasPrefix(line, "[Mongo-Hook] ") || !strings.HasSuffix(line, "\n") {
+			t.Fatalf("malformed output %q", line)
+		}
+	}
+}
+
+func TestForceFlushContext(t *testing.T) {
+	exec := newCtxExec()
+	h := New(SetExec(exec), SetAutoStart(false), SetRetry(5, time.Hour),
+		SetErrorHandler(func(*logrus.Entry, error) {}))
+	h.Fire(testEntry(logrus.InfoLevel, "a"))
+	h.Fire(testEntry(logrus.InfoLevel, "b"))
+
+	// 写入与重试退避在ctx取消时中止
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- h.ForceFlush(ctx) }()
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("ForceFlush = %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ForceFlush ignored the cancelled context")
+	}
+	if n := len(exec.started); n != 1 {
+		t.Fatalf("writes started = %d, want 1", n)
+	}
+}
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// IDGenerator 根据条目生成文档的 _id，返回nil时由MongoDB生成
+type IDGenerator func(*logrus.Entry) interface{}
+
+// SetIDGenerator 设置默认文档 _id 的生成方式，确定的 _id 配合SetUpsert可避免重放时写入重复的文档
+func SetIDGenerator(generator IDGenerator) Option {
+	return func(o *options) {
+		o.idGenerator = generator
+	}
+}
+
+// HashID 使用级别、时间与消息的哈希值作为 _id
+func HashID(entry *logrus.Entry) interface{} {
+	h := sha1.New()
+	h.Write([]byte(entry.Level.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(entry.Time.UnixNano(), 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(entry.Message))
+	return hex.EncodeToString(h.Sum(nil))
+}
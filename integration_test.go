@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testURL 返回集成测试使用的服务端地址
+func testURL() string {
+	return os.Getenv("MONGO_TEST_URL")
+}
+
+// testCollection 连接 MONGO_TEST_URL 指定的服务端并返回一个新的集合，测试结束时删除集合。
+// 未设置 MONGO_TEST_URL 时跳过测试
+func testCollection(t *testing.T) *mongo.Collection {
+	t.Helper()
+	url := testURL()
+	if url == "" {
+		t.Skip("MONGO_TEST_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, mopts.Client().ApplyURI(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("logger_test_%d", time.Now().UnixNano())
+	coll := client.Database("logger_test").Collection(name)
+	t.Cleanup(func() {
+		coll.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return coll
+}
+
+func TestSyncQueryable(t *testing.T) {
+	coll := testCollection(t)
+	h := New(SetExec(NewExecWithCollection(coll)), SetSync(true))
+	defer h.Close()
+
+	for i := 1; i <= 3; i++ {
+		h.Fire(testEntry(logrus.InfoLevel, "sync"))
+		n, err := coll.CountDocuments(context.Background(), bson.M{"message": "sync"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(i) {
+			t.Fatalf("found %d documents after %d writes", n, i)
+		}
+	}
+}
+
+func TestReplayFileTwiceWritesNoDuplicates(t *testing.T) {
+	coll := testCollection(t)
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "dead")
+	var buf bytes.Buffer
+	for _, msg := range []string{"a", "b", "c"} {
+		line, err := marshalEntry(testEntry(logrus.ErrorLevel, msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(append(line, '\n'))
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		written, failed, err := ReplayFile(path, NewExecWithCollection(coll))
+		if err != nil || written != 3 || failed != 0 {
+			t.Fatalf("replay %d: written = %d, failed = %d, err = %v", i, written, failed, err)
+		}
+	}
+	n, err := coll.CountDocuments(context.Background(), bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("found %d documents after replaying twice, want 3", n)
+	}
+}
+
+func TestTimeRangeQuery(t *testing.T) {
+	coll := testCollection(t)
+	h := New(SetExec(NewExecWithCollection(coll)), SetSync(true))
+	defer h.Close()
+
+	start := time.Now().Truncate(time.Millisecond)
+	for i := 0; i < 5; i++ {
+		entry := testEntry(logrus.InfoLevel, fmt.Sprint(i))
+		entry.Time = start.Add(time.Duration(i) * time.Hour)
+		h.Fire(entry)
+	}
+	filter := bson.M{"time": bson.M{"$gte": start.Add(time.Hour), "$lt": start.Add(3 * time.Hour)}}
+	cur, err := coll.Find(context.Background(), filter, mopts.Find().SetSort(bson.M{"time": 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var docs []bson.M
+	if err := cur.All(context.Background(), &docs); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0]["message"] != "1" || docs[1]["message"] != "2" {
+		t.Fatalf("found %v, want the entries logged in hours 1 and 2", docs)
+	}
+}
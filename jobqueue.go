@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/pm-esd/queue"
+	"github.com/sirupsen/logrus"
+)
+
+// JobQueue 执行写入任务的队列，推送的任务实现了Job接口。
+// 默认队列的每个任务从钩子的缓冲区中按SetPriorityLevels的优先级取出最早的条目
+type JobQueue interface {
+	Run()
+	Push(job queue.Jober)
+	Terminate()
+	Len() int
+}
+
+// Job 推送到JobQueue的写入任务，Level 为推送任务时条目的级别，自定义队列可据此决定执行顺序。
+// 使用SetQueue时任务执行时写入缓冲区中该级别最早的条目，该级别的条目已被淘汰时写入最早的条目
+type Job interface {
+	queue.Jober
+	Level() logrus.Level
+}
+
+// SetQueue 设置执行写入任务的队列，设置后忽略SetMaxQueues与SetMaxWorkers创建的默认队列，
+// 钩子启动时调用Run并在Flush时调用Terminate。队列先执行的任务对应的条目先写入，
+// 如优先执行错误级别的任务；缓冲区容量与溢出策略仍由SetMaxQueues与SetOverflowPolicy控制
+func SetQueue(q JobQueue) Option {
+	return func(o *options) {
+		o.fixed("SetQueue")
+		o.queue = q
+	}
+}
+
+// writeJob 写入一个条目的任务，seq 为任务的编号
+type writeJob struct {
+	hook  *Hook
+	level logrus.Level
+	seq   uint64
+}
+
+func (j *writeJob) Job() {
+	defer j.hook.pending.done(j.seq)
+	j.hook.runJob(j.level)
+}
+
+func (j *writeJob) Level() logrus.Level {
+	return j.level
+}
+
+// jobQueue 默认的任务队列，记录等待执行的任务数
+type jobQueue struct {
+	q       *queue.Queue
+	pending int64
+}
+
+func newJobQueue(maxCapacity, maxThread int) *jobQueue {
+	return &jobQueue{q: queue.NewQueue(maxCapacity, maxThread)}
+}
+
+func (q *jobQueue) Run() {
+	q.q.Run()
+}
+
+func (q *jobQueue) Push(job queue.Jober) {
+	atomic.AddInt64(&q.pending, 1)
+	q.q.Push(queue.NewJob(job, func(v interface{}) {
+		atomic.AddInt64(&q.pending, -1)
+		v.(queue.Jober).Job()
+	}))
+}
+
+func (q *jobQueue) Terminate() {
+	q.q.Terminate()
+}
+
+func (q *jobQueue) Len() int {
+	return int(atomic.LoadInt64(&q.pending))
+}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// LevelFormat 默认文档中级别的保存方式
+type LevelFormat int
+
+const (
+	// LevelString 以名称保存级别，如 "error"(默认)
+	LevelString LevelFormat = iota
+	// LevelNumber 以logrus的数值保存级别，数值越小越严重
+	LevelNumber
+	// LevelBoth 以名称保存在level，数值保存在level_num
+	LevelBoth
+)
+
+// SetLevelFormat 设置默认文档中级别的保存方式
+func SetLevelFormat(format LevelFormat) Option {
+	return func(o *options) {
+		o.levelFormat = format
+	}
+}
+
+// levelFields 按保存方式写入级别字段
+func levelFields(item bson.M, prefix string, format LevelFormat, level logrus.Level) {
+	switch format {
+	case LevelNumber:
+		reserve(item, prefix, "level", uint32(level))
+	case LevelBoth:
+		reserve(item, prefix, "level", level.String())
+		reserve(item, prefix, "level_num", uint32(level))
+	default:
+		reserve(item, prefix, "level", level.String())
+	}
+}
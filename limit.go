@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// truncated 截断后追加的标记
+const truncated = "...[truncated]"
+
+// SetMaxFieldBytes 设置消息和字符串字段的最大字节数，超出时截断并追加 ...[truncated]，同时设置 _truncated: true
+func SetMaxFieldBytes(n int) Option {
+	return func(o *options) {
+		o.maxFieldBytes = n
+	}
+}
+
+// SetMaxDocBytes 设置条目的最大字节数(按BSON编码估算)，截断后仍超出时丢弃条目并输出警告。
+// MongoDB单个文档不能超过16MB
+func SetMaxDocBytes(n int) Option {
+	return func(o *options) {
+		o.maxDocBytes = n
+	}
+}
+
+// truncate 按字节数截断字符串，不截断多字节字符
+func truncate(s string, n int) (string, bool) {
+	if len(s) <= n {
+		return s, false
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + truncated, true
+}
+
+// limit 截断复制后条目中过长的字段，返回false时条目超出最大字节数
+func (h *Hook) limit(entry *logrus.Entry) bool {
+	if n := h.options().maxFieldBytes; n > 0 {
+		var cut bool
+		if msg, ok := truncate(entry.Message, n); ok {
+			entry.Message, cut = msg, true
+		}
+		for k, v := range entry.Data {
+			if s, ok := v.(string); ok {
+				if s, ok = truncate(s, n); ok {
+					entry.Data[k], cut = s, true
+				}
+			}
+		}
+		if cut {
+			entry.Data["_truncated"] = true
+		}
+	}
+
+	if n := h.options().maxDocBytes; n > 0 {
+		doc := make(bson.M, len(entry.Data)+2)
+		for k, v := range entry.Data {
+			doc[k] = v
+		}
+		doc["level"] = entry.Level
+		doc["message"] = entry.Message
+		b, err := bson.Marshal(doc)
+		if err == nil && len(b) > n {
+			h.warn("Entry dropped: %d bytes exceeds the limit of %d bytes", len(b), n)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MemoryExec 将条目保存在内存中的Exec，用于单元测试
+type MemoryExec struct {
+	lock    sync.Mutex
+	entries []*logrus.Entry
+}
+
+// NewMemoryExec 创建一个内存Exec
+func NewMemoryExec() *MemoryExec {
+	return &MemoryExec{}
+}
+
+// Exec 保存条目的副本，开启SetPoolEntries时条目被回收后副本保持不变
+func (m *MemoryExec) Exec(entry *logrus.Entry) error {
+	entry = cloneEntry(entry)
+	m.lock.Lock()
+	m.entries = append(m.entries, entry)
+	m.lock.Unlock()
+	return nil
+}
+
+// BatchExec 保存一批条目的副本
+func (m *MemoryExec) BatchExec(entries []*logrus.Entry) error {
+	clones := make([]*logrus.Entry, len(entries))
+	for i, entry := range entries {
+		clones[i] = cloneEntry(entry)
+	}
+	m.lock.Lock()
+	m.entries = append(m.entries, clones...)
+	m.lock.Unlock()
+	return nil
+}
+
+// Ping 始终返回nil
+func (m *MemoryExec) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close 始终返回nil，关闭后仍可读取已保存的条目
+func (m *MemoryExec) Close() error {
+	return nil
+}
+
+// Entries 返回已保存条目的副本
+func (m *MemoryExec) Entries() []*logrus.Entry {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]*logrus.Entry(nil), m.entries...)
+}
+
+// Reset 清空已保存的条目
+func (m *MemoryExec) Reset() {
+	m.lock.Lock()
+	m.entries = nil
+	m.lock.Unlock()
+}
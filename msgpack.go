@@ -0,0 +1,443 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errMsgpack 数据不是有效的msgpack格式
+var errMsgpack = errors.New("invalid msgpack data")
+
+// MsgpackSerializer 以msgpack格式序列化条目，体积小于JSON，可作为SetSerializer的参数，
+// 需同时使用SetDeserializer(MsgpackDeserializer)。
+// 字段值支持基本类型、time.Time、error、切片与字符串键的映射，其余类型保存为 fmt.Sprint 的结果
+func MsgpackSerializer(entry *logrus.Entry) ([]byte, error) {
+	var w msgpackWriter
+	w.mapHeader(4)
+	w.string("level")
+	w.string(entry.Level.String())
+	w.string("time")
+	w.time(entry.Time)
+	w.string("message")
+	w.string(entry.Message)
+	w.string("data")
+	w.mapHeader(len(entry.Data))
+	for k, v := range entry.Data {
+		w.string(k)
+		w.value(v)
+	}
+	return w.buf, nil
+}
+
+// MsgpackDeserializer 将MsgpackSerializer序列化的数据还原为条目，整数字段还原为int64
+func MsgpackDeserializer(buf []byte) (*logrus.Entry, error) {
+	r := msgpackReader{buf: buf}
+	v, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	item, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errMsgpack
+	}
+	name, _ := item["level"].(string)
+	level, err := logrus.ParseLevel(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := logrus.NewEntry(nil)
+	entry.Level = level
+	entry.Time, _ = item["time"].(time.Time)
+	entry.Message, _ = item["message"].(string)
+	data, _ := item["data"].(map[string]interface{})
+	for k, v := range data {
+		entry.Data[k] = v
+	}
+	return entry, nil
+}
+
+// msgpackWriter msgpack编码
+type msgpackWriter struct {
+	buf []byte
+}
+
+func (w *msgpackWriter) byte(b byte) {
+	w.buf = append(w.buf, b)
+}
+
+func (w *msgpackWriter) uint16(code byte, n uint16) {
+	w.buf = append(w.buf, code, byte(n>>8), byte(n))
+}
+
+func (w *msgpackWriter) uint32(code byte, n uint32) {
+	w.buf = append(w.buf, code, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func (w *msgpackWriter) uint64(code byte, n uint64) {
+	w.buf = append(w.buf, code)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *msgpackWriter) mapHeader(n int) {
+	switch {
+	case n < 16:
+		w.byte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		w.uint16(0xde, uint16(n))
+	default:
+		w.uint32(0xdf, uint32(n))
+	}
+}
+
+func (w *msgpackWriter) arrayHeader(n int) {
+	switch {
+	case n < 16:
+		w.byte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		w.uint16(0xdc, uint16(n))
+	default:
+		w.uint32(0xdd, uint32(n))
+	}
+}
+
+func (w *msgpackWriter) string(s string) {
+	switch n := len(s); {
+	case n < 32:
+		w.byte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		w.uint16(0xda, uint16(n))
+	default:
+		w.uint32(0xdb, uint32(n))
+	}
+	w.buf = append(w.buf, s...)
+}
+
+func (w *msgpackWriter) bytes(b []byte) {
+	switch n := len(b); {
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		w.uint16(0xc5, uint16(n))
+	default:
+		w.uint32(0xc6, uint32(n))
+	}
+	w.buf = append(w.buf, b...)
+}
+
+func (w *msgpackWriter) int(n int64) {
+	switch {
+	case n >= 0:
+		w.uint(uint64(n))
+	case n >= -32:
+		w.byte(byte(n))
+	case n >= math.MinInt8:
+		w.buf = append(w.buf, 0xd0, byte(n))
+	case n >= math.MinInt16:
+		w.uint16(0xd1, uint16(n))
+	case n >= math.MinInt32:
+		w.uint32(0xd2, uint32(n))
+	default:
+		w.uint64(0xd3, uint64(n))
+	}
+}
+
+func (w *msgpackWriter) uint(n uint64) {
+	switch {
+	case n < 128:
+		w.byte(byte(n))
+	case n <= math.MaxUint8:
+		w.buf = append(w.buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		w.uint16(0xcd, uint16(n))
+	case n <= math.MaxUint32:
+		w.uint32(0xce, uint32(n))
+	default:
+		w.uint64(0xcf, n)
+	}
+}
+
+// time 以96位的时间戳扩展类型(-1)保存时间
+func (w *msgpackWriter) time(t time.Time) {
+	w.buf = append(w.buf, 0xc7, 12)
+	w.uint32(0xff, uint32(t.Nanosecond()))
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(t.Unix()))
+	w.buf = append(w.buf, b[:]...)
+}
+
+func (w *msgpackWriter) value(v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.byte(0xc0)
+	case bool:
+		if v {
+			w.byte(0xc3)
+		} else {
+			w.byte(0xc2)
+		}
+	case int:
+		w.int(int64(v))
+	case int8:
+		w.int(int64(v))
+	case int16:
+		w.int(int64(v))
+	case int32:
+		w.int(int64(v))
+	case int64:
+		w.int(v)
+	case uint:
+		w.uint(uint64(v))
+	case uint8:
+		w.uint(uint64(v))
+	case uint16:
+		w.uint(uint64(v))
+	case uint32:
+		w.uint(uint64(v))
+	case uint64:
+		w.uint(v)
+	case float32:
+		w.uint32(0xca, math.Float32bits(v))
+	case float64:
+		w.uint64(0xcb, math.Float64bits(v))
+	case string:
+		w.string(v)
+	case []byte:
+		w.bytes(v)
+	case time.Time:
+		w.time(v)
+	case time.Duration:
+		w.int(int64(v))
+	case error:
+		w.string(errorMessage(v))
+	default:
+		w.reflect(reflect.ValueOf(v))
+	}
+}
+
+// reflect 编码切片与映射，映射的键转换为字符串
+func (w *msgpackWriter) reflect(rv reflect.Value) {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		w.arrayHeader(rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			w.value(rv.Index(i).Interface())
+		}
+	case reflect.Map:
+		w.mapHeader(rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			w.string(fmt.Sprint(iter.Key().Interface()))
+			w.value(iter.Value().Interface())
+		}
+	default:
+		w.string(fmt.Sprint(rv.Interface()))
+	}
+}
+
+// msgpackReader msgpack解码
+type msgpackReader struct {
+	buf []byte
+	off int
+}
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || len(r.buf)-r.off < n {
+		return nil, errMsgpack
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+// length 读取n字节的大端长度
+func (r *msgpackReader) length(n int) (int, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var l uint64
+	for _, c := range b {
+		l = l<<8 | uint64(c)
+	}
+	if l > uint64(len(r.buf)) {
+		return 0, errMsgpack
+	}
+	return int(l), nil
+}
+
+func (r *msgpackReader) value() (interface{}, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+	switch {
+	case code < 0x80:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xf0 == 0x80:
+		return r.mapValue(int(code & 0x0f))
+	case code&0xf0 == 0x90:
+		return r.arrayValue(int(code & 0x0f))
+	case code&0xe0 == 0xa0:
+		return r.stringValue(int(code & 0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.length(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.next(n)
+		return append([]byte(nil), b...), err
+	case 0xc7, 0xd6, 0xd7:
+		return r.timeValue(code)
+	case 0xca:
+		b, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := r.next(1 << (code - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		b, err := r.next(size)
+		if err != nil {
+			return nil, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		// 按位宽做符号扩展
+		shift := uint(64 - 8*size)
+		return int64(n<<shift) >> shift, nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.length(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.stringValue(n)
+	case 0xdc, 0xdd:
+		n, err := r.length(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.arrayValue(n)
+	case 0xde, 0xdf:
+		n, err := r.length(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.mapValue(n)
+	}
+	return nil, errMsgpack
+}
+
+func (r *msgpackReader) stringValue(n int) (interface{}, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (r *msgpackReader) arrayValue(n int) (interface{}, error) {
+	arr := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+	return arr, nil
+}
+
+func (r *msgpackReader) mapValue(n int) (interface{}, error) {
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, errMsgpack
+		}
+		if m[key], err = r.value(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// timeValue 解码时间戳扩展类型的32位、64位与96位格式
+func (r *msgpackReader) timeValue(code byte) (interface{}, error) {
+	size := 4
+	switch code {
+	case 0xd7:
+		size = 8
+	case 0xc7:
+		n, err := r.length(1)
+		if err != nil {
+			return nil, err
+		}
+		size = n
+	}
+	b, err := r.next(1 + size)
+	if err != nil {
+		return nil, err
+	}
+	if int8(b[0]) != -1 {
+		return nil, errMsgpack
+	}
+	b = b[1:]
+	switch size {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		n := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(n&(1<<34-1)), int64(n>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))), nil
+	}
+	return nil, errMsgpack
+}
@@ -0,0 +1,203 @@
+package logger
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// decode 解码单个msgpack值
+func decode(buf []byte) (interface{}, error) {
+	r := msgpackReader{buf: buf}
+	return r.value()
+}
+
+// encode 编码单个值
+func encode(v interface{}) []byte {
+	var w msgpackWriter
+	w.value(v)
+	return w.buf
+}
+
+func TestMsgpackDecodeTypeCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+		want interface{}
+	}{
+		{"positive fixint", []byte{0x7f}, int64(127)},
+		{"negative fixint", []byte{0xe0}, int64(-32)},
+		{"fixmap", []byte{0x81, 0xa1, 'a', 0x01}, map[string]interface{}{"a": int64(1)}},
+		{"fixarray", []byte{0x92, 0x01, 0xc0}, []interface{}{int64(1), nil}},
+		{"fixstr", []byte{0xa2, 'h', 'i'}, "hi"},
+		{"nil", []byte{0xc0}, nil},
+		{"false", []byte{0xc2}, false},
+		{"true", []byte{0xc3}, true},
+		{"bin 8", []byte{0xc4, 2, 1, 2}, []byte{1, 2}},
+		{"bin 16", []byte{0xc5, 0, 1, 9}, []byte{9}},
+		{"bin 32", []byte{0xc6, 0, 0, 0, 1, 9}, []byte{9}},
+		{"float 32", []byte{0xca, 0x3f, 0xc0, 0, 0}, float64(1.5)},
+		{"float 64", []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, float64(1.5)},
+		{"uint 8", []byte{0xcc, 0xff}, int64(255)},
+		{"uint 16", []byte{0xcd, 0xff, 0xff}, int64(65535)},
+		{"uint 32", []byte{0xce, 0xff, 0xff, 0xff, 0xff}, int64(math.MaxUint32)},
+		{"uint 64", []byte{0xcf, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, int64(math.MaxInt64)},
+		{"uint 64 overflow", []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, uint64(math.MaxUint64)},
+		{"int 8", []byte{0xd0, 0x80}, int64(math.MinInt8)},
+		{"int 16", []byte{0xd1, 0x80, 0}, int64(math.MinInt16)},
+		{"int 32", []byte{0xd2, 0x80, 0, 0, 0}, int64(math.MinInt32)},
+		{"int 64", []byte{0xd3, 0x80, 0, 0, 0, 0, 0, 0, 0}, int64(math.MinInt64)},
+		{"int 8 -1", []byte{0xd0, 0xff}, int64(-1)},
+		{"str 8", []byte{0xd9, 1, 'a'}, "a"},
+		{"str 16", []byte{0xda, 0, 1, 'a'}, "a"},
+		{"str 32", []byte{0xdb, 0, 0, 0, 1, 'a'}, "a"},
+		{"array 16", []byte{0xdc, 0, 1, 0x01}, []interface{}{int64(1)}},
+		{"array 32", []byte{0xdd, 0, 0, 0, 1, 0x01}, []interface{}{int64(1)}},
+		{"map 16", []byte{0xde, 0, 1, 0xa1, 'a', 0xc3}, map[string]interface{}{"a": true}},
+		{"map 32", []byte{0xdf, 0, 0, 0, 1, 0xa1, 'a', 0xc2}, map[string]interface{}{"a": false}},
+		{"timestamp 32", []byte{0xd6, 0xff, 0, 0, 0, 1}, time.Unix(1, 0)},
+		{"timestamp 64", []byte{0xd7, 0xff, 0, 0, 0, 0x04, 0, 0, 0, 2}, time.Unix(2, 1)},
+		{"timestamp 96", []byte{0xc7, 12, 0xff, 0, 0, 0, 3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, time.Unix(-1, 3)},
+	}
+	for _, tt := range tests {
+		v, err := decode(tt.buf)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if want, ok := tt.want.(time.Time); ok {
+			if got, ok := v.(time.Time); !ok || !got.Equal(want) {
+				t.Errorf("%s: %#v, want %s", tt.name, v, want)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("%s: %#v, want %#v", tt.name, v, tt.want)
+		}
+	}
+}
+
+func TestMsgpackDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"empty", nil},
+		{"reserved code", []byte{0xc1}},
+		{"unsupported ext", []byte{0xd4, 0x01, 0x00}},
+		{"non-string key", []byte{0x81, 0x01, 0x01}},
+		{"timestamp with other ext type", []byte{0xd6, 0x01, 0, 0, 0, 1}},
+		{"timestamp of invalid size", []byte{0xc7, 5, 0xff, 0, 0, 0, 0, 1}},
+		{"length beyond buffer", []byte{0xdb, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, tt := range tests {
+		if v, err := decode(tt.buf); err == nil {
+			t.Errorf("%s: decoded %#v, want an error", tt.name, v)
+		}
+	}
+}
+
+func TestMsgpackTruncated(t *testing.T) {
+	values := []interface{}{
+		int64(1 << 40), int64(math.MinInt64), uint64(math.MaxUint64), 1.5, float32(1.5),
+		strings.Repeat("a", 40), strings.Repeat("a", 300), strings.Repeat("a", 70000),
+		[]byte{1, 2, 3}, make([]byte, 300), make([]byte, 70000),
+		time.Unix(1700000000, 123), []interface{}{"a", int64(1)},
+		map[string]interface{}{"a": "b", "c": []interface{}{true}},
+	}
+	for _, v := range values {
+		buf := encode(v)
+		if _, err := decode(buf); err != nil {
+			t.Fatalf("%T: %v", v, err)
+		}
+		// 任意位置截断的数据都返回错误，不会引发panic
+		for n := 0; n < len(buf); n++ {
+			if got, err := decode(buf[:n]); err == nil {
+				t.Fatalf("%T truncated to %d of %d bytes: decoded %#v", v, n, len(buf), got)
+			}
+		}
+	}
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{0, int64(0)},
+		{127, int64(127)},
+		{128, int64(128)},
+		{-1, int64(-1)},
+		{-32, int64(-32)},
+		{-33, int64(-33)},
+		{int8(math.MinInt8), int64(math.MinInt8)},
+		{int16(math.MinInt16), int64(math.MinInt16)},
+		{int32(math.MinInt32), int64(math.MinInt32)},
+		{int64(math.MinInt64), int64(math.MinInt64)},
+		{math.MinInt8 - 1, int64(math.MinInt8 - 1)},
+		{math.MinInt16 - 1, int64(math.MinInt16 - 1)},
+		{math.MinInt32 - 1, int64(math.MinInt32 - 1)},
+		{uint(1), int64(1)},
+		{uint8(math.MaxUint8), int64(math.MaxUint8)},
+		{uint16(math.MaxUint16), int64(math.MaxUint16)},
+		{uint32(math.MaxUint32), int64(math.MaxUint32)},
+		{uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{float32(0.25), float64(0.25)},
+		{math.Pi, math.Pi},
+		{"", ""},
+		{strings.Repeat("a", 31), strings.Repeat("a", 31)},
+		{strings.Repeat("a", 32), strings.Repeat("a", 32)},
+		{strings.Repeat("a", 256), strings.Repeat("a", 256)},
+		{strings.Repeat("a", 65536), strings.Repeat("a", 65536)},
+		{[]byte{7}, []byte{7}},
+		{make([]byte, 256), make([]byte, 256)},
+		{time.Second, int64(time.Second)},
+		{errors.New("failed"), "failed"},
+		{[]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{make([]string, 16), []interface{}{"", "", "", "", "", "", "", "", "", "", "", "", "", "", "", ""}},
+		{map[int]string{1: "a"}, map[string]interface{}{"1": "a"}},
+		{struct{}{}, "{}"},
+	}
+	for _, tt := range tests {
+		v, err := decode(encode(tt.v))
+		if err != nil {
+			t.Fatalf("%#v: %v", tt.v, err)
+		}
+		if !reflect.DeepEqual(v, tt.want) {
+			t.Fatalf("%T: decoded %#v, want %#v", tt.v, v, tt.want)
+		}
+	}
+}
+
+func TestMsgpackEntry(t *testing.T) {
+	entry := testEntry(logrus.WarnLevel, "a")
+	entry.Time = time.Unix(1700000000, 123456789)
+	entry.Data["n"] = -5
+	entry.Data["at"] = entry.Time
+	buf, err := MsgpackSerializer(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := MsgpackDeserializer(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Level != entry.Level || got.Message != "a" || !got.Time.Equal(entry.Time) {
+		t.Fatalf("entry = %s %q %s", got.Level, got.Message, got.Time)
+	}
+	if at, _ := got.Data["at"].(time.Time); got.Data["n"] != int64(-5) || !at.Equal(entry.Time) {
+		t.Fatalf("data = %v", got.Data)
+	}
+	for n := 0; n < len(buf); n++ {
+		if _, err := MsgpackDeserializer(buf[:n]); err == nil {
+			t.Fatalf("entry truncated to %d of %d bytes was decoded", n, len(buf))
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MultiError 多个Exec返回的错误
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// errorOrNil 没有错误时返回nil
+func (m MultiError) errorOrNil() error {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+type multiExec struct {
+	execs []ExecCloser
+}
+
+// MultiExec 将条目同时写入多个Exec，某个Exec失败不影响其他Exec，钩子重试时只写入失败的Exec
+func MultiExec(execs ...ExecCloser) ExecCloser {
+	return &multiExec{execs: execs}
+}
+
+func (m *multiExec) bind(cfg func() *options) {
+	for _, exec := range m.execs {
+		if b, ok := exec.(optionsBinder); ok {
+			b.bind(cfg)
+		}
+	}
+}
+
+func (m *multiExec) cancellable(batch bool) bool {
+	for _, exec := range m.execs {
+		if !cancellable(exec, batch) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *multiExec) Exec(entry *logrus.Entry) error {
+	return m.each(entry, func(exec ExecCloser) error {
+		return exec.Exec(entry)
+	})
+}
+
+func (m *multiExec) ExecContext(ctx context.Context, entry *logrus.Entry) error {
+	return m.each(entry, func(exec ExecCloser) error {
+		return execContext(ctx, exec, entry)
+	})
+}
+
+func (m *multiExec) BatchExec(entries []*logrus.Entry) error {
+	return m.batch(entries, batchExec)
+}
+
+func (m *multiExec) BatchExecContext(ctx context.Context, entries []*logrus.Entry) error {
+	return m.batch(entries, func(exec ExecCloser, entries []*logrus.Entry) error {
+		return batchExecContext(ctx, exec, entries)
+	})
+}
+
+// multiSentKey 条目上下文中记录已写入的Exec的键
+type multiSentKey struct {
+	m *multiExec
+}
+
+// sent 返回条目已写入成功的Exec，没有记录时返回nil
+func (m *multiExec) sent(entry *logrus.Entry) []bool {
+	if entry.Context == nil {
+		return nil
+	}
+	sent, _ := entry.Context.Value(multiSentKey{m}).([]bool)
+	return sent
+}
+
+// remember 在条目的上下文中记录已写入成功的Exec，重试时只写入失败的Exec。
+// 记录随条目释放一起清除
+func (m *multiExec) remember(entry *logrus.Entry, sent []bool) {
+	if m.sent(entry) != nil {
+		return
+	}
+	parent := entry.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	entry.Context = context.WithValue(parent, multiSentKey{m}, sent)
+}
+
+// each 将条目写入尚未写入成功的Exec，部分Exec失败时记录写入成功的Exec
+func (m *multiExec) each(entry *logrus.Entry, write func(ExecCloser) error) error {
+	sent := m.sent(entry)
+	var errs MultiError
+	var succeeded []int
+	for i, exec := range m.execs {
+		if sent != nil && sent[i] {
+			continue
+		}
+		if err := write(exec); err != nil {
+			errs = append(errs, err)
+		} else {
+			succeeded = append(succeeded, i)
+		}
+	}
+	if len(errs) > 0 && len(succeeded) > 0 {
+		if sent == nil {
+			sent = make([]bool, len(m.execs))
+			m.remember(entry, sent)
+		}
+		for _, i := range succeeded {
+			sent[i] = true
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// batch 将每个条目写入尚未写入成功的Exec。部分条目在所有Exec中写入成功时返回BatchError，
+// 钩子只重试其余条目；重试的条目只写入此前失败的Exec
+func (m *multiExec) batch(entries []*logrus.Entry, write func(ExecCloser, []*logrus.Entry) error) error {
+	sent := make([][]bool, len(entries))
+	for i, entry := range entries {
+		sent[i] = m.sent(entry)
+	}
+	ok := make([][]bool, len(m.execs))
+	var errs MultiError
+	for t, exec := range m.execs {
+		var pending []*logrus.Entry
+		var indices []int
+		for i, entry := range entries {
+			if sent[i] == nil || !sent[i][t] {
+				pending = append(pending, entry)
+				indices = append(indices, i)
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		err := write(exec, pending)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		var batchErr *BatchError
+		if err != nil && !errors.As(err, &batchErr) {
+			continue
+		}
+		ok[t] = make([]bool, len(entries))
+		for j, i := range indices {
+			if batchErr == nil || !containsIndex(batchErr.Indices, j) {
+				ok[t][i] = true
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var failed []int
+	for i, entry := range entries {
+		entrySent := sent[i]
+		var done, missing bool
+		for t := range m.execs {
+			switch {
+			case entrySent != nil && entrySent[t]:
+			case ok[t] != nil && ok[t][i]:
+				if entrySent == nil {
+					entrySent = make([]bool, len(m.execs))
+				}
+				entrySent[t] = true
+				done = true
+			default:
+				missing = true
+			}
+		}
+		if !missing {
+			continue
+		}
+		failed = append(failed, i)
+		if done {
+			m.remember(entry, entrySent)
+		}
+	}
+	if len(failed) == len(entries) {
+		return errs
+	}
+	return &BatchError{Indices: failed, Err: errs}
+}
+
+func (m *multiExec) Ping(ctx context.Context) error {
+	var errs MultiError
+	for _, exec := range m.execs {
+		if err := exec.Ping(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.errorOrNil()
+}
+
+func (m *multiExec) Close() error {
+	var errs MultiError
+	for _, exec := range m.execs {
+		if err := exec.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.errorOrNil()
+}
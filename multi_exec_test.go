@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMultiExecRetriesFailedTargets(t *testing.T) {
+	for _, batchSize := range []int{0, 2} {
+		var lock sync.Mutex
+		var calls []string
+		failed := false
+		// 第一次写入b时失败
+		flaky := funcExec(func(entry *logrus.Entry) error {
+			lock.Lock()
+			defer lock.Unlock()
+			calls = append(calls, entry.Message)
+			if entry.Message == "b" && !failed {
+				failed = true
+				return errors.New("write failed")
+			}
+			return nil
+		})
+		good := NewMemoryExec()
+		h := New(SetExec(MultiExec(good, flaky)), SetMaxWorkers(1), SetBatchSize(batchSize),
+			SetRetry(3, time.Millisecond),
+			SetErrorHandler(func(entry *logrus.Entry, err error) { t.Errorf("error = %v", err) }))
+		h.Fire(testEntry(logrus.InfoLevel, "a"))
+		h.Fire(testEntry(logrus.InfoLevel, "b"))
+		h.Flush()
+
+		// 重试只写入失败的Exec，写入成功的Exec不会重复收到条目
+		assertMessages(t, good.Entries(), "a", "b")
+		lock.Lock()
+		if len(calls) != 3 || calls[2] != "b" {
+			t.Fatalf("batch size %d: flaky calls = %q, want [a b b]", batchSize, calls)
+		}
+		lock.Unlock()
+	}
+}
+
+func TestMultiExecBatchError(t *testing.T) {
+	errWrite := errors.New("write failed")
+	fail := funcExec(func(*logrus.Entry) error { return errWrite })
+	failB := funcExec(func(entry *logrus.Entry) error {
+		if entry.Message == "b" {
+			return errWrite
+		}
+		return nil
+	})
+	m := MultiExec(NewMemoryExec(), failB).(*multiExec)
+	entries := []*logrus.Entry{testEntry(logrus.InfoLevel, "a"), testEntry(logrus.InfoLevel, "b")}
+
+	// a在所有Exec中写入成功，只有b需要重试
+	var batchErr *BatchError
+	if err := m.BatchExec(entries); !errors.As(err, &batchErr) || len(batchErr.Indices) != 1 || batchErr.Indices[0] != 1 {
+		t.Fatalf("error = %v, want a BatchError for index 1", err)
+	}
+	if sent := m.sent(entries[1]); len(sent) != 2 || !sent[0] || sent[1] {
+		t.Fatalf("sent = %v, want [true false]", sent)
+	}
+	if sent := m.sent(entries[0]); sent != nil {
+		t.Fatalf("entry written to every exec recorded %v", sent)
+	}
+
+	// 所有条目都失败时返回MultiError
+	m = MultiExec(fail, fail).(*multiExec)
+	var errs MultiError
+	if err := m.BatchExec(entries); !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("error = %v, want a MultiError", err)
+	}
+}
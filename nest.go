@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetNestFields 设置默认文档中移入子文档的字段，键为原字段名，值为以 . 分隔的目标路径(如 status -> http.status)。
+// 路径上已有非文档的值时保留原字段，并交给错误处理程序(未设置时输出到out)
+func SetNestFields(fields map[string]string) Option {
+	return func(o *options) {
+		o.nestFields = fields
+	}
+}
+
+// nest 将文档中的字段移入子文档
+func (e *defaultExec) nest(item bson.M, entry *logrus.Entry) {
+	o := e.options()
+	if len(o.nestFields) == 0 {
+		return
+	}
+
+	sources := make([]string, 0, len(o.nestFields))
+	for src := range o.nestFields {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	for _, src := range sources {
+		v, ok := item[src]
+		if !ok {
+			continue
+		}
+		path := o.nestFields[src]
+		if err := nestValue(item, src, path, v); err != nil {
+			if !o.handleError(err, entry) {
+				o.warn("%s", err.Error())
+			}
+		}
+	}
+}
+
+// nestValue 将src的值写入path，路径上的子文档会被复制，不修改条目中的原值
+func nestValue(item bson.M, src, path string, v interface{}) error {
+	parts := strings.Split(path, ".")
+	cur := item
+	for i, part := range parts[:len(parts)-1] {
+		next, ok := cur[part]
+		if !ok {
+			m := make(bson.M)
+			cur[part] = m
+			cur = m
+			continue
+		}
+
+		var m bson.M
+		switch sub := next.(type) {
+		case bson.M:
+			m = sub
+		case map[string]interface{}:
+			m = sub
+		default:
+			return fmt.Errorf("nest field %s: %s is not a document", src, strings.Join(parts[:i+1], "."))
+		}
+		cp := make(bson.M, len(m)+1)
+		for k, v := range m {
+			cp[k] = v
+		}
+		cur[part] = cp
+		cur = cp
+	}
+
+	if src != path {
+		delete(item, src)
+	}
+	cur[parts[len(parts)-1]] = v
+	return nil
+}
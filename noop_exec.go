@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type noopExec struct{}
+
+// NoopExec 丢弃所有条目的Exec，用于只挂载钩子而不写入的灰度发布
+func NoopExec() ExecCloser {
+	return noopExec{}
+}
+
+func (noopExec) Exec(entry *logrus.Entry) error {
+	return nil
+}
+
+func (noopExec) BatchExec(entries []*logrus.Entry) error {
+	return nil
+}
+
+func (noopExec) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (noopExec) Close() error {
+	return nil
+}
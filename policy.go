@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// PolicyOptions 某一级别条目的写入策略，零值的字段使用全局参数
+type PolicyOptions struct {
+	// Collection 默认Exec写入的集合名称，优先于集合路由与集合模板
+	Collection string
+	// MaxAttempts 最大尝试次数，1表示不重试
+	MaxAttempts int
+	// Backoff 重试的初始退避时间
+	Backoff time.Duration
+	// WriteConcern 默认Exec写入时的WriteConcern，取值同SetWriteConcern，审计条目仍使用审计的WriteConcern
+	WriteConcern interface{}
+	// NoDeadLetter 写入失败时不写入死信
+	NoDeadLetter bool
+}
+
+// SetLevelPolicy 设置某一级别条目的写入策略，如错误日志多次重试、调试日志不重试且不写入死信。
+// 批量写入时使用批次中最大的尝试次数与退避时间
+func SetLevelPolicy(level logrus.Level, policy PolicyOptions) Option {
+	return func(o *options) {
+		o.fixed("SetLevelPolicy")
+		policies := make(map[logrus.Level]PolicyOptions, len(o.levelPolicies)+1)
+		for l, p := range o.levelPolicies {
+			policies[l] = p
+		}
+		policies[level] = policy
+		o.levelPolicies = policies
+	}
+}
+
+// retryPolicy 返回条目的最大尝试次数与初始退避时间
+func (h *Hook) retryPolicy(entries []*logrus.Entry) (maxAttempts int, backoff time.Duration) {
+	o := h.options()
+	if len(o.levelPolicies) == 0 || len(entries) == 0 {
+		return o.maxAttempts, o.backoff
+	}
+	for _, entry := range entries {
+		p := o.levelPolicies[entry.Level]
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = o.maxAttempts
+		}
+		if p.Backoff == 0 {
+			p.Backoff = o.backoff
+		}
+		if p.MaxAttempts > maxAttempts {
+			maxAttempts = p.MaxAttempts
+		}
+		if p.Backoff > backoff {
+			backoff = p.Backoff
+		}
+	}
+	return maxAttempts, backoff
+}
+
+// deadLetters 返回需要写入死信的条目
+func (h *Hook) deadLetters(entries []*logrus.Entry) []*logrus.Entry {
+	if len(h.options().levelPolicies) == 0 {
+		return entries
+	}
+	var dead []*logrus.Entry
+	for _, entry := range entries {
+		if !h.options().levelPolicies[entry.Level].NoDeadLetter {
+			dead = append(dead, entry)
+		}
+	}
+	return dead
+}
+
+// policyCollection 返回级别策略设置的集合名称
+func (e *defaultExec) policyCollection(level logrus.Level) string {
+	return e.options().levelPolicies[level].Collection
+}
+
+// setupPolicies 解析级别策略的WriteConcern
+func (e *defaultExec) setupPolicies(o *options) {
+	e.levelWC = nil
+	for level, p := range o.levelPolicies {
+		wc, err := parseWriteConcern(p.WriteConcern)
+		if err != nil {
+			o.warn("Write concern error for level %s: %s", level, err.Error())
+			continue
+		}
+		if wc == nil {
+			continue
+		}
+		if e.levelWC == nil {
+			e.levelWC = make(map[logrus.Level]*writeconcern.WriteConcern)
+		}
+		e.levelWC[level] = wc
+	}
+}
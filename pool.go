@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetPoolEntries 设置是否复用复制后的条目以减少内存分配，默认关闭。
+// 开启后条目写入完成即被回收，Exec、过滤器、错误处理程序与结果回调不能在返回后继续持有条目，
+// 过滤器返回的新条目同样会被回收
+func SetPoolEntries(poolEntries bool) Option {
+	return func(o *options) {
+		o.poolEntries = poolEntries
+	}
+}
+
+// entryPool 复用的条目
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return &logrus.Entry{Data: make(logrus.Fields, 6)}
+	},
+}
+
+// newEntry 返回一个空的条目，开启复用时从条目池中获取
+func (h *Hook) newEntry(logger *logrus.Logger) *logrus.Entry {
+	if !h.options().poolEntries {
+		entry := logrus.NewEntry(logger)
+		entry.Data = make(logrus.Fields)
+		return entry
+	}
+	entry := entryPool.Get().(*logrus.Entry)
+	entry.Logger = logger
+	return entry
+}
+
+// release 开启复用时回收处理完成的条目
+func (h *Hook) release(entries ...*logrus.Entry) {
+	if !h.options().poolEntries {
+		return
+	}
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		data := entry.Data
+		for k := range data {
+			delete(data, k)
+		}
+		if data == nil {
+			data = make(logrus.Fields, 6)
+		}
+		*entry = logrus.Entry{Data: data}
+		entryPool.Put(entry)
+	}
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPoolEntriesMemoryExec(t *testing.T) {
+	replace := func(entry *logrus.Entry) *logrus.Entry {
+		if entry.Message != "replaced" {
+			return entry
+		}
+		replaced := entry.WithField("replaced", true)
+		replaced.Level, replaced.Message = entry.Level, entry.Message
+		return replaced
+	}
+	for _, sync := range []bool{false, true} {
+		exec := NewMemoryExec()
+		h := New(SetExec(exec), SetSync(sync), SetMaxWorkers(1), SetPoolEntries(true), SetFilter(replace))
+		for i := 0; i < 3; i++ {
+			entry := testEntry(logrus.InfoLevel, strconv.Itoa(i))
+			entry.Data["i"] = i
+			h.Fire(entry)
+		}
+		h.Fire(testEntry(logrus.InfoLevel, "replaced"))
+		h.Flush()
+
+		// 条目被回收后保存的副本保持不变
+		entries := exec.Entries()
+		assertMessages(t, entries, "0", "1", "2", "replaced")
+		for i, entry := range entries[:3] {
+			if entry.Data["i"] != i {
+				t.Fatalf("sync %v: entry %d data = %v", sync, i, entry.Data)
+			}
+		}
+		if entries[3].Data["replaced"] != true {
+			t.Fatalf("sync %v: replaced entry data = %v", sync, entries[3].Data)
+		}
+	}
+}
+
+func TestPoolEntriesReleasedOnce(t *testing.T) {
+	h := New(SetExec(NewMemoryExec()), SetSync(true), SetPoolEntries(true))
+	defer h.Close()
+	h.Fire(testEntry(logrus.InfoLevel, "a"))
+
+	// 同一个条目被回收两次时，之后两次获取可能得到同一个条目
+	a := h.newEntry(nil)
+	b := h.newEntry(nil)
+	if a == b {
+		t.Fatal("the written entry was released twice")
+	}
+}
+
+// nopExec 丢弃写入的条目
+type nopExec struct{}
+
+func (nopExec) Exec(entry *logrus.Entry) error { return nil }
+func (nopExec) Ping(ctx context.Context) error { return nil }
+func (nopExec) Close() error                   { return nil }
+
+func BenchmarkPoolEntries(b *testing.B) {
+	for _, pool := range []bool{false, true} {
+		b.Run("pool="+strconv.FormatBool(pool), func(b *testing.B) {
+			h := New(SetExec(nopExec{}), SetSync(true), SetPoolEntries(pool))
+			defer h.Close()
+			entry := testEntry(logrus.InfoLevel, "a")
+			entry.Data["user"] = "alice"
+			entry.Data["request_id"] = 42
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.Fire(entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+//go:build prometheus
+// +build prometheus
+
+package logger
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheusCollector 将钩子的运行统计导出为Prometheus指标
+type prometheusCollector struct {
+	hook          *Hook
+	queueLength   *prometheus.Desc
+	queueCapacity *prometheus.Desc
+	enqueued      *prometheus.Desc
+	dropped       *prometheus.Desc
+	blocked       *prometheus.Desc
+	execErrors    *prometheus.Desc
+	execLatency   *prometheus.Desc
+}
+
+// NewPrometheusCollector 创建导出钩子运行统计的Prometheus采集器，需使用 prometheus 构建标签编译
+func NewPrometheusCollector(h *Hook, namespace string) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "mongo_hook", name), help, nil, nil)
+	}
+	return &prometheusCollector{
+		hook:          h,
+		queueLength:   desc("queue_length", "Number of entries waiting in the queue."),
+		queueCapacity: desc("queue_capacity", "Capacity of the queue."),
+		enqueued:      desc("enqueued_total", "Total number of entries enqueued."),
+		dropped:       desc("dropped_total", "Total number of entries dropped."),
+		blocked:       desc("blocked_nanoseconds_total", "Total time Fire spent blocked on a full queue."),
+		execErrors:    desc("exec_errors_total", "Total number of failed Exec calls."),
+		execLatency:   desc("exec_duration_seconds", "Duration of Exec calls."),
+	}
+}
+
+func (c *prometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueLength
+	ch <- c.queueCapacity
+	ch <- c.enqueued
+	ch <- c.dropped
+	ch <- c.blocked
+	ch <- c.execErrors
+	ch <- c.execLatency
+}
+
+func (c *prometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.hook.Stats()
+	ch <- prometheus.MustNewConstMetric(c.queueLength, prometheus.GaugeValue, float64(stats.QueueLength))
+	ch <- prometheus.MustNewConstMetric(c.queueCapacity, prometheus.GaugeValue, float64(stats.QueueCapacity))
+	ch <- prometheus.MustNewConstMetric(c.enqueued, prometheus.CounterValue, float64(stats.Enqueued))
+	ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(stats.Dropped))
+	ch <- prometheus.MustNewConstMetric(c.blocked, prometheus.CounterValue, float64(stats.BlockedDuration.Nanoseconds()))
+	ch <- prometheus.MustNewConstMetric(c.execErrors, prometheus.CounterValue, float64(stats.ExecErrors))
+	ch <- prometheus.MustNewConstSummary(c.execLatency, stats.Execs, stats.ExecDuration.Seconds(), nil)
+}
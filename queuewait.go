@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// queueStamp 条目入队的时间与在队列中等待的时长
+type queueStamp struct {
+	enqueued time.Time
+	wait     time.Duration
+}
+
+type queueStampKey struct{}
+
+// stamp 设置了写入延迟观察者时记录条目的入队时间
+func (h *Hook) stamp(entry *logrus.Entry) {
+	if h.options().latencyObserver == nil {
+		return
+	}
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	entry.Context = context.WithValue(ctx, queueStampKey{}, &queueStamp{enqueued: h.options().clock()})
+}
+
+// dequeued 记录条目在队列中等待的时长
+func (h *Hook) dequeued(entry *logrus.Entry) {
+	if s := stampOf(entry); s != nil {
+		s.wait = h.options().clock().Sub(s.enqueued)
+	}
+}
+
+func stampOf(entry *logrus.Entry) *queueStamp {
+	if entry.Context == nil {
+		return nil
+	}
+	s, _ := entry.Context.Value(queueStampKey{}).(*queueStamp)
+	return s
+}
+
+// queueWait 返回条目在队列中等待的最长时长，同步写入的条目为0
+func queueWait(entries ...*logrus.Entry) time.Duration {
+	var wait time.Duration
+	for _, entry := range entries {
+		if s := stampOf(entry); s != nil && s.wait > wait {
+			wait = s.wait
+		}
+	}
+	return wait
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// options 返回当前的参数。参数在运行时只会整体替换，读取到的参数不会再被修改
+func (h *Hook) options() *options {
+	return h.cfg.Load().(*options)
+}
+
+// Reconfigure 在运行时原子地应用一组参数，参数无效时返回错误且不做任何修改。
+// 级别、采样、过滤器、扩展字段、脱敏、重试、错误处理、字段名称、集合路由等运行时读取的参数立即生效；
+// Exec、队列、工作线程、优先级、批量写入、去重、熔断、基数限制、溢出文件、死信及其序列化方式、时钟，
+// 以及默认Exec创建时使用的WriteConcern、级别策略、TTL、时间字段、固定集合与时序集合只能在创建时设置，
+// 传入这些参数时返回ErrFixedOption
+func (h *Hook) Reconfigure(opt ...Option) error {
+	h.cfgLock.Lock()
+	defer h.cfgLock.Unlock()
+
+	cur := h.options()
+	next := *cur
+	next.fixedOptions = nil
+	for _, o := range opt {
+		o(&next)
+	}
+	if len(next.fixedOptions) > 0 {
+		return fmt.Errorf("%w: %s", ErrFixedOption, strings.Join(next.fixedOptions, ", "))
+	}
+	if err := next.validate(); err != nil {
+		return err
+	}
+	next.reportInvalid()
+	h.cfg.Store(&next)
+	return nil
+}
+
+// fixed 记录设置了只能在创建时设置的参数
+func (o *options) fixed(name string) {
+	o.fixedOptions = append(o.fixedOptions, name)
+}
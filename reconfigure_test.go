@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pm-esd/mongodb"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReconfigureRejectsFixedOptions(t *testing.T) {
+	exec := NewMemoryExec()
+	h := New(SetExec(exec), SetMaxQueues(8), SetMaxWorkers(1))
+	defer h.Close()
+
+	var dead bytes.Buffer
+	fixed := []Option{
+		SetMaxQueues(64), SetMaxWorkers(4), SetBatchSize(10), SetPriorityLevels(logrus.ErrorLevel),
+		SetDedup(time.Minute), SetCircuitBreaker(1, time.Minute), SetCardinalityLimit("user", 1, time.Minute),
+		SetDeadLetter(&dead), SetSpillFile("spill"), SetClock(time.Now), SetTTL(time.Hour),
+		SetWriteConcern("majority"), SetExec(NewMemoryExec()),
+	}
+	for i, opt := range fixed {
+		// 只能在创建时设置的参数返回错误，同时传入的其他参数也不生效
+		if err := h.Reconfigure(SetLevels(logrus.ErrorLevel), opt); !errors.Is(err, ErrFixedOption) {
+			t.Fatalf("%d: error = %v, want ErrFixedOption", i, err)
+		}
+	}
+	if err := h.Reconfigure(SetMaxWorkers(4), SetBatchSize(10)); err == nil ||
+		err.Error() != ErrFixedOption.Error()+": SetMaxWorkers, SetBatchSize" {
+		t.Fatalf("error = %v, want the option names", err)
+	}
+
+	h.Fire(testEntry(logrus.InfoLevel, "info"))
+	h.Drain()
+	assertMessages(t, exec.Entries(), "info")
+}
+
+func TestReconfigureDefaultExecOptions(t *testing.T) {
+	exec := NewExec(new(mongodb.MongoDBClient), "logs").(*defaultExec)
+	h := New(SetExec(exec), SetAutoStart(false))
+	defer h.Close()
+
+	// 默认Exec读取钩子当前的参数，运行时修改的字段名称与集合路由立即生效
+	err := h.Reconfigure(SetFieldNames(map[string]string{"message": "msg"}),
+		SetCollectionRouter(func(*logrus.Entry) string { return "routed" }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := testEntry(logrus.InfoLevel, "a")
+	if doc := exec.defaultDocument(entry); doc["msg"] != "a" {
+		t.Fatalf("document = %v, want msg field", doc)
+	}
+	if name := exec.collectionName(entry); name != "routed" {
+		t.Fatalf("collection = %q, want routed", name)
+	}
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const (
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// errReconnecting 连接断开且尚未到下一次重连时间
+var errReconnecting = errors.New("mongo connection lost, waiting to reconnect")
+
+// SetReconnect 设置默认Exec在连接断开后是否自动重连(默认开启)
+func SetReconnect(reconnect bool) Option {
+	return func(o *options) {
+		o.reconnect = reconnect
+	}
+}
+
+// connector 能够重新建立连接的客户端
+type connector interface {
+	Connect() error
+}
+
+// reconnectState 默认Exec的连接状态
+type reconnectState struct {
+	lock         sync.Mutex
+	broken       bool
+	reconnecting bool
+	backoff      time.Duration
+	next         time.Time
+}
+
+// ensureConnected 连接断开后按退避时间尝试重连，未到重连时间或其他写入正在重连时直接返回错误。
+// 重连在锁外进行，同一时间只有一个写入重连，ctx取消时中止重连
+func (e *defaultExec) ensureConnected(ctx context.Context) error {
+	if !e.options().reconnect {
+		return nil
+	}
+
+	now := e.options().clock()
+	r := &e.reconn
+	r.lock.Lock()
+	if !r.broken {
+		r.lock.Unlock()
+		return nil
+	}
+	if r.reconnecting || now.Before(r.next) {
+		r.lock.Unlock()
+		return errReconnecting
+	}
+	r.reconnecting = true
+	r.lock.Unlock()
+
+	err := e.connect(ctx)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reconnecting = false
+	if err != nil {
+		r.backoff *= 2
+		if r.backoff > maxReconnectBackoff {
+			r.backoff = maxReconnectBackoff
+		}
+		r.next = now.Add(r.backoff)
+		return err
+	}
+	r.broken = false
+	return nil
+}
+
+// checkConnection 写入出现连接错误时标记连接断开
+func (e *defaultExec) checkConnection(err error) {
+	if err == nil || !e.options().reconnect || !isConnectionError(err) {
+		return
+	}
+
+	r := &e.reconn
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.broken {
+		r.broken = true
+		r.backoff = minReconnectBackoff
+		r.next = e.options().clock().Add(r.backoff)
+	}
+}
+
+// connect 重新建立连接，客户端不支持重连时通过ping确认连接恢复，
+// 二者均不可用时直接放行，由下一次写入确认连接状态
+func (e *defaultExec) connect(ctx context.Context) error {
+	if c, ok := interface{}(e.sess).(connector); ok && e.sess != nil {
+		return c.Connect()
+	}
+	db, err := e.database()
+	if err != nil {
+		return nil
+	}
+	return db.Client().Ping(ctx, readpref.Primary())
+}
+
+func isConnectionError(err error) bool {
+	if errors.Is(err, mongo.ErrClientDisconnected) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.HasErrorLabel("NetworkError")
+}
@@ -0,0 +1,258 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.lock.Lock()
+	c.now = c.now.Add(d)
+	c.lock.Unlock()
+}
+
+// disconnectedExec 使用未连接的客户端创建默认Exec，重连时ping失败
+func disconnectedExec(t *testing.T, clock *fakeClock) *defaultExec {
+	client, err := mongo.NewClient(mopts.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewExecWithCollection(client.Database("logger_test").Collection("logs")).(*defaultExec)
+	opts := defaultOptions
+	opts.clock = clock.Now
+	e.cfg = func() *options { return &opts }
+	return e
+}
+
+func TestReconnectBackoff(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	e := disconnectedExec(t, clock)
+
+	if err := e.ensureConnected(context.Background()); err != nil {
+		t.Fatalf("connected exec returned %v", err)
+	}
+	e.checkConnection(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")})
+	if err := e.ensureConnected(context.Background()); err != errReconnecting {
+		t.Fatalf("error before backoff = %v, want errReconnecting", err)
+	}
+
+	// 每次重连失败后退避时间加倍，最长为maxReconnectBackoff
+	want := minReconnectBackoff
+	for i := 0; i < 12; i++ {
+		clock.Add(want)
+		if err := e.ensureConnected(context.Background()); err == nil || err == errReconnecting {
+			t.Fatalf("attempt %d: error = %v, want a connect error", i, err)
+		}
+		if want *= 2; want > maxReconnectBackoff {
+			want = maxReconnectBackoff
+		}
+		if e.reconn.backoff != want {
+			t.Fatalf("attempt %d: backoff = %s, want %s", i, e.reconn.backoff, want)
+		}
+		if err := e.ensureConnected(context.Background()); err != errReconnecting {
+			t.Fatalf("attempt %d: error = %v, want errReconnecting", i, err)
+		}
+	}
+}
+
+func TestReconnectDisabled(t *testing.T) {
+	e := disconnectedExec(t, &fakeClock{now: time.Now()})
+	e.options().reconnect = false
+	e.checkConnection(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")})
+	if err := e.ensureConnected(context.Background()); err != nil {
+		t.Fatalf("error = %v, want nil", err)
+	}
+}
+
+// silentClient 连接到接受连接但从不应答的服务端，命令一直等到上下文取消
+func silentClient(t *testing.T) *mongo.Client {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	client, err := mongo.Connect(context.Background(), mopts.Client().
+		SetHosts([]string{ln.Addr().String()}).
+		SetDirect(true).
+		SetConnectTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+	return client
+}
+
+func TestReconnectSingleFlight(t *testing.T) {
+	client := silentClient(t)
+	clock := &fakeClock{now: time.Now()}
+	e := NewExecWithCollection(client.Database("logger_test").Collection("logs")).(*defaultExec)
+	opts := defaultOptions
+	opts.clock = clock.Now
+	e.cfg = func() *options { return &opts }
+	e.checkConnection(&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")})
+	clock.Add(minReconnectBackoff)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- e.ensureConnected(ctx) }()
+	for {
+		e.reconn.lock.Lock()
+		reconnecting := e.reconn.reconnecting
+		e.reconn.lock.Unlock()
+		if reconnecting {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 重连进行中时其他写入不等待锁，直接返回
+	if err := e.ensureConnected(context.Background()); err != errReconnecting {
+		t.Fatalf("error during reconnect = %v, want errReconnecting", err)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil || err == errReconnecting {
+			t.Fatalf("cancelled reconnect returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnect ignored the cancelled context")
+	}
+}
+
+// tcpProxy 转发到服务端的TCP代理，停止后断开全部连接，可在同一地址重新启动
+type tcpProxy struct {
+	addr   string
+	target string
+	lock   sync.Mutex
+	ln     net.Listener
+	conns  []net.Conn
+}
+
+func (p *tcpProxy) start(t *testing.T) {
+	ln, err := net.Listen("tcp", p.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.lock.Lock()
+	p.ln, p.addr = ln, ln.Addr().String()
+	p.lock.Unlock()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			upstream, err := net.Dial("tcp", p.target)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			p.lock.Lock()
+			p.conns = append(p.conns, conn, upstream)
+			p.lock.Unlock()
+			go io.Copy(upstream, conn)
+			go io.Copy(conn, upstream)
+		}
+	}()
+}
+
+func (p *tcpProxy) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.ln.Close()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
+
+func TestReconnectResumes(t *testing.T) {
+	coll := testCollection(t)
+	proxy := &tcpProxy{addr: "127.0.0.1:0", target: mopts.Client().ApplyURI(testURL()).Hosts[0]}
+	proxy.start(t)
+	defer proxy.stop()
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, mopts.Client().
+		SetHosts([]string{proxy.addr}).
+		SetDirect(true).
+		SetServerSelectionTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	var lock sync.Mutex
+	var failures int
+	exec := NewExecWithCollection(client.Database(coll.Database().Name()).Collection(coll.Name()))
+	h := New(SetExec(exec), SetSync(true), SetOut(ioutil.Discard),
+		SetErrorHandler(func(entry *logrus.Entry, err error) {
+			lock.Lock()
+			failures++
+			lock.Unlock()
+		}))
+	defer h.Close()
+
+	h.Fire(testEntry(logrus.InfoLevel, "before"))
+	proxy.stop()
+	h.Fire(testEntry(logrus.InfoLevel, "down"))
+	proxy.start(t)
+
+	// 连接恢复后条目继续写入
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		written := h.Stats().Written
+		h.Fire(testEntry(logrus.InfoLevel, "after"))
+		if h.Stats().Written > written {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("writes did not resume after the connection came back")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if failures == 0 {
+		t.Fatal("write while the connection was down did not fail")
+	}
+	for _, msg := range []string{"before", "after"} {
+		if n, err := coll.CountDocuments(ctx, bson.M{"message": msg}); err != nil || n == 0 {
+			t.Fatalf("%s: count = %d, err = %v", msg, n, err)
+		}
+	}
+}
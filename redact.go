@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted 脱敏后的字段值
+const redacted = "[REDACTED]"
+
+type redactPattern struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// SetRedactKeys 设置需要脱敏的字段，写入前其值将替换为 [REDACTED]
+func SetRedactKeys(keys ...string) Option {
+	return func(o *options) {
+		o.redactKeys = append(o.redactKeys[:len(o.redactKeys):len(o.redactKeys)], keys...)
+	}
+}
+
+// SetRedactPattern 设置消息的脱敏规则，写入前消息中匹配re的内容将替换为replacement
+func SetRedactPattern(re *regexp.Regexp, replacement string) Option {
+	return func(o *options) {
+		o.redactPatterns = append(o.redactPatterns[:len(o.redactPatterns):len(o.redactPatterns)], redactPattern{
+			re:          re,
+			replacement: replacement,
+		})
+	}
+}
+
+// redact 对复制后的条目脱敏，不影响进程内的日志输出
+func (h *Hook) redact(entry *logrus.Entry) {
+	for _, key := range h.options().redactKeys {
+		if _, ok := entry.Data[key]; ok {
+			entry.Data[key] = redacted
+		}
+	}
+	for _, p := range h.options().redactPatterns {
+		entry.Message = p.re.ReplaceAllString(entry.Message, p.replacement)
+	}
+}
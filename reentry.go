@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reentry 记录正在调用错误处理程序或内部日志的goroutine，调用期间记录的日志不再进入钩子。
+// 没有调用进行时Fire只读取计数，不解析goroutine编号
+type reentry struct {
+	n    int32
+	lock sync.Mutex
+	ids  map[uint64]int
+}
+
+// enter 标记当前goroutine正在调用钩子外部的处理程序，返回取消标记的函数。r为nil时不做标记
+func (r *reentry) enter() (leave func()) {
+	if r == nil {
+		return func() {}
+	}
+	id := goroutineID()
+	r.lock.Lock()
+	if r.ids == nil {
+		r.ids = make(map[uint64]int)
+	}
+	r.ids[id]++
+	r.lock.Unlock()
+	atomic.AddInt32(&r.n, 1)
+
+	return func() {
+		atomic.AddInt32(&r.n, -1)
+		r.lock.Lock()
+		if r.ids[id]--; r.ids[id] == 0 {
+			delete(r.ids, id)
+		}
+		r.lock.Unlock()
+	}
+}
+
+// active 判断当前goroutine是否正在调用处理程序，没有调用进行时不解析goroutine编号
+func (r *reentry) active() bool {
+	if atomic.LoadInt32(&r.n) == 0 {
+		return false
+	}
+	id := goroutineID()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.ids[id] > 0
+}
+
+// handleError 将err交给错误处理程序处理entries，未设置错误处理程序时返回false
+func (o *options) handleError(err error, entries ...*logrus.Entry) bool {
+	handler := o.errorHandler
+	if handler == nil {
+		return false
+	}
+	defer o.reentry.enter()()
+	for _, entry := range entries {
+		handler(entry, err)
+	}
+	return true
+}
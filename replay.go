@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// ReplayFile 将死信文件(逐行JSON)或溢出文件(长度前缀格式)中的条目通过exec重新写入数据库，
+// 返回写入成功与失败的条目数，无法解析的记录跳过并计入失败数。
+// 默认Exec按 _id 替换写入(未设置ID生成器时使用HashID)，重复重放不会写入重复的文档，
+// 因此exec应为单独创建的实例，不要与正在使用的钩子共用。
+// opt 用于设置解析方式(SetDeserializer)等参数，设置了解析方式时文件按长度前缀格式读取
+func ReplayFile(path string, exec ExecCloser, opt ...Option) (written, failed int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	opts := defaultOptions
+	if e, ok := exec.(*defaultExec); ok && e.cfg != nil {
+		opts = *e.cfg()
+	}
+	for _, o := range opt {
+		o(&opts)
+	}
+	deserialize := opts.deserialize()
+	if b, ok := exec.(optionsBinder); ok {
+		opts.upsert = !opts.appendOnly
+		if opts.idGenerator == nil {
+			opts.idGenerator = HashID
+		}
+		b.bind(func() *options { return &opts })
+	}
+
+	write := func(buf []byte) {
+		entry, err := deserialize(buf)
+		if err == nil {
+			err = exec.Exec(entry)
+		}
+		if err != nil {
+			failed++
+			return
+		}
+		written++
+	}
+
+	r := bufio.NewReader(file)
+	first, err := r.Peek(1)
+	if err == io.EOF {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if first[0] == '{' && opts.deserializer == nil {
+		err = readLines(r, write)
+	} else {
+		var fi os.FileInfo
+		if fi, err = file.Stat(); err == nil {
+			err = readRecords(r, fi.Size(), write)
+		}
+	}
+	return written, failed, err
+}
+
+// readLines 逐行读取死信文件，跳过空行
+func readLines(r *bufio.Reader, fn func([]byte)) error {
+	for {
+		line, err := r.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			fn(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readRecords 按长度前缀格式读取溢出文件，长度超出文件大小或末尾不完整的记录交给fn解析失败
+func readRecords(r *bufio.Reader, size int64, fn func([]byte)) error {
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+			return nil
+		} else if err == io.ErrUnexpectedEOF {
+			fn(nil)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		n := int64(binary.BigEndian.Uint32(header[:]))
+		if n > size {
+			fn(nil)
+			return nil
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err == io.EOF || err == io.ErrUnexpectedEOF {
+			fn(nil)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		fn(buf)
+	}
+}
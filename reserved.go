@@ -0,0 +1,18 @@
+package logger
+
+// SetReservedPrefix 设置钩子注入字段(file、func、hostname、stack、goid及默认文档的level、message、created、时间字段)
+// 与条目已有字段同名时注入字段使用的前缀，默认为 _，两者的值都会保存
+func SetReservedPrefix(prefix string) Option {
+	return func(o *options) {
+		o.reservedPrefix = prefix
+	}
+}
+
+// reserve 写入钩子注入的字段，与已有字段同名时加上前缀，返回实际写入的字段名
+func reserve(data map[string]interface{}, prefix, key string, value interface{}) string {
+	if _, ok := data[key]; ok {
+		key = prefix + key
+	}
+	data[key] = value
+	return key
+}
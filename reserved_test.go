@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestReservedFieldCollision(t *testing.T) {
+	tests := []struct {
+		opts   []Option
+		prefix string
+	}{
+		{nil, "_"},
+		{[]Option{SetReservedPrefix("hook_")}, "hook_"},
+	}
+	for _, tt := range tests {
+		exec := NewMemoryExec()
+		h := New(append(tt.opts, SetExec(exec), SetSync(true))...)
+		log := logrus.New()
+		log.SetOutput(ioutil.Discard)
+		log.SetReportCaller(true)
+		log.AddHook(h)
+
+		log.WithFields(logrus.Fields{"file": "user.txt", "level": "user", "message": "user"}).Info("a")
+		entry := exec.Entries()[0]
+		if entry.Data["file"] != "user.txt" {
+			t.Fatalf("user file = %v", entry.Data["file"])
+		}
+		if file, _ := entry.Data[tt.prefix+"file"].(string); !strings.Contains(file, "reserved_test.go:") {
+			t.Fatalf("hook file = %v", entry.Data[tt.prefix+"file"])
+		}
+
+		doc := (&defaultExec{cfg: h.options}).defaultDocument(entry)
+		if doc["level"] != "user" || doc[tt.prefix+"level"] != "info" {
+			t.Fatalf("level = %v, %slevel = %v", doc["level"], tt.prefix, doc[tt.prefix+"level"])
+		}
+		if doc["message"] != "user" || doc[tt.prefix+"message"] != "a" {
+			t.Fatalf("message = %v, %smessage = %v", doc["message"], tt.prefix, doc[tt.prefix+"message"])
+		}
+	}
+}
+
+func TestStructuredCallerKeepsUserFields(t *testing.T) {
+	tests := []struct {
+		fields logrus.Fields
+		keep   []string
+		drop   []string
+	}{
+		// 用户字段带有保留前缀时钩子注入的字段没有前缀
+		{logrus.Fields{"_file": "user.txt", "_func": "user"}, []string{"_file", "_func"}, []string{"file", "func"}},
+		// 用户已有 file 与 func 时钩子注入的字段带有前缀
+		{logrus.Fields{"file": "user.txt", "func": "user"}, []string{"file", "func"}, []string{"_file", "_func"}},
+	}
+	for i, tt := range tests {
+		exec := NewMemoryExec()
+		h := New(SetExec(exec), SetSync(true), SetCallerStructured(true))
+		log := logrus.New()
+		log.SetOutput(ioutil.Discard)
+		log.SetReportCaller(true)
+		log.AddHook(h)
+
+		log.WithFields(tt.fields).Info("a")
+		doc := (&defaultExec{cfg: h.options}).defaultDocument(exec.Entries()[0])
+		for _, k := range tt.keep {
+			if doc[k] != tt.fields[k] {
+				t.Fatalf("%d: %s = %v, want %v", i, k, doc[k], tt.fields[k])
+			}
+		}
+		for _, k := range tt.drop {
+			if v, ok := doc[k]; ok {
+				t.Fatalf("%d: injected %s = %v was kept", i, k, v)
+			}
+		}
+		if _, ok := doc["caller"]; !ok {
+			t.Fatalf("%d: caller = %v", i, doc["caller"])
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetRetry 设置写入失败时的最大尝试次数与初始退避时间，每次重试退避时间翻倍
+func SetRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.maxAttempts = maxAttempts
+		o.backoff = backoff
+	}
+}
+
+// retry 按条目的重试策略执行fn直到成功、熔断器打开、ctx取消或达到最大尝试次数，返回尝试次数与最后一次的错误
+func (h *Hook) retry(ctx context.Context, fn func() error, entries ...*logrus.Entry) (int, error) {
+	maxAttempts, backoff := h.retryPolicy(entries)
+	attempts := 1
+	err := fn()
+	for err != nil && err != ErrCircuitOpen && attempts < maxAttempts {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, err
+		case <-timer.C:
+		}
+		backoff *= 2
+		attempts++
+		err = fn()
+	}
+	return attempts, err
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sampler 条目采样器，返回false时丢弃条目
+type Sampler func(*logrus.Entry) bool
+
+// SetSampler 设置条目采样器，在Fire中入队前执行，默认不对Error及以上级别采样
+func SetSampler(sampler Sampler) Option {
+	return func(o *options) {
+		o.sampler = sampler
+	}
+}
+
+// SetSampleErrors 设置是否对Error及以上级别的条目采样
+func SetSampleErrors(sampleErrors bool) Option {
+	return func(o *options) {
+		o.sampleErrors = sampleErrors
+	}
+}
+
+// RateSampler 按级别每n个条目保留1个
+func RateSampler(n int) Sampler {
+	var counters [logrus.TraceLevel + 1]uint64
+	return func(entry *logrus.Entry) bool {
+		if n <= 1 || entry.Level > logrus.TraceLevel {
+			return true
+		}
+		c := atomic.AddUint64(&counters[entry.Level], 1)
+		return (c-1)%uint64(n) == 0
+	}
+}
+
+// NewAdaptiveSampler 返回按队列压力采样的采样器，pressure 返回队列的使用比例(0到1，如Hook.QueuePressure)。
+// 压力低于start时保留全部条目，超过后保留的比例线性降低，队列已满时只保留不参与采样的条目；
+// 队列排空后恢复保留全部条目。Error及以上级别的条目默认不参与采样，不会因此丢失。
+// 钩子创建后通过 h.Reconfigure(SetSampler(NewAdaptiveSampler(h.QueuePressure, 0.5))) 设置
+func NewAdaptiveSampler(pressure func() float64, start float64) Sampler {
+	if start < 0 || start >= 1 {
+		start = 0
+	}
+	return func(*logrus.Entry) bool {
+		p := pressure()
+		if p <= start {
+			return true
+		}
+		keep := 1 - (p-start)/(1-start)
+		return rand.Float64() < keep
+	}
+}
+
+// QueuePressure 返回缓冲区的使用比例，0表示为空，1表示已满
+func (h *Hook) QueuePressure() float64 {
+	return float64(h.buf.len()) / float64(h.buf.capacity)
+}
+
+// sample 判断条目是否保留
+func (h *Hook) sample(entry *logrus.Entry) bool {
+	sampler := h.options().sampler
+	if sampler == nil {
+		return true
+	}
+	if entry.Level <= logrus.ErrorLevel && !h.options().sampleErrors {
+		return true
+	}
+	return sampler(entry)
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAdaptiveSamplerRamp(t *testing.T) {
+	var pressure float64
+	sampler := NewAdaptiveSampler(func() float64 { return pressure }, 0.5)
+	entry := testEntry(logrus.InfoLevel, "a")
+
+	// 压力先升高到队列已满再回落到空，保留比例随之降低后恢复
+	ramp := []struct {
+		pressure float64
+		keep     float64
+	}{
+		{0, 1}, {0.25, 1}, {0.5, 1}, {0.625, 0.75}, {0.75, 0.5}, {0.875, 0.25}, {1, 0},
+		{0.75, 0.5}, {0.5, 1}, {0, 1},
+	}
+	const n = 4000
+	for _, step := range ramp {
+		pressure = step.pressure
+		kept := 0
+		for i := 0; i < n; i++ {
+			if sampler(entry) {
+				kept++
+			}
+		}
+		if got := float64(kept) / n; math.Abs(got-step.keep) > 0.05 {
+			t.Fatalf("pressure %.3f: kept %.3f, want %.3f", step.pressure, got, step.keep)
+		}
+	}
+}
+
+func TestAdaptiveSamplerUnderLoad(t *testing.T) {
+	exec := newGateExec()
+	h := New(SetExec(exec), SetMaxQueues(100), SetMaxWorkers(1), SetOverflowPolicy(DropNewest),
+		SetPriorityLevels(logrus.ErrorLevel))
+	if err := h.Reconfigure(SetSampler(NewAdaptiveSampler(h.QueuePressure, 0.5))); err != nil {
+		t.Fatal(err)
+	}
+	h.Fire(testEntry(logrus.InfoLevel, "blocked"))
+	<-exec.started
+	go func() {
+		for range exec.started {
+		}
+	}()
+
+	// 工作线程阻塞时负载逐渐升高，队列过半后开始丢弃采样的条目，高优先级的错误条目全部保留
+	for i := 0; i < 1000; i++ {
+		h.Fire(testEntry(logrus.InfoLevel, "info"))
+		if i%20 == 0 {
+			h.Fire(testEntry(logrus.ErrorLevel, "error"))
+		}
+	}
+	stats := h.Stats()
+	if stats.Sampled == 0 {
+		t.Fatal("no entries were sampled out under load")
+	}
+	if p := h.QueuePressure(); p < 0.5 {
+		t.Fatalf("queue pressure = %.2f, want at least 0.5", p)
+	}
+
+	// 负载回落并排空后恢复保留全部条目
+	close(exec.gate)
+	h.Drain()
+	sampled := h.Stats().Sampled
+	for i := 0; i < 20; i++ {
+		h.Fire(testEntry(logrus.InfoLevel, "after"))
+		h.Drain()
+	}
+	if n := h.Stats().Sampled; n != sampled {
+		t.Fatalf("%d entries sampled out after the queue drained", n-sampled)
+	}
+	h.Flush()
+
+	errors := 0
+	for _, entry := range exec.Entries() {
+		if entry.Level == logrus.ErrorLevel {
+			errors++
+		}
+	}
+	if errors != 50 {
+		t.Fatalf("%d error entries written, want 50", errors)
+	}
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SetTTL 设置日志的过期时间，默认Exec会在时间字段(默认为time)上建立TTL索引，不足一秒的部分向上取整
+func SetTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.fixed("SetTTL")
+		o.ttl = ttl
+	}
+}
+
+// SetTimeField 设置默认文档中以BSON日期保存条目时间的字段名称(默认为time)，TTL索引建立在该字段上
+func SetTimeField(timeField string) Option {
+	return func(o *options) {
+		o.fixed("SetTimeField")
+		o.timeField = timeField
+	}
+}
+
+// SetCapped 设置集合的最大字节数，默认Exec会在集合不存在时创建固定集合。
+// 固定集合不支持TTL索引，与SetTTL同时设置时只创建固定集合
+func SetCapped(sizeBytes int64) Option {
+	return func(o *options) {
+		o.fixed("SetCapped")
+		o.cappedSize = sizeBytes
+	}
+}
+
+// SetCappedMaxDocs 设置固定集合的最大文档数，需与SetCapped同时使用
+func SetCappedMaxDocs(n int64) Option {
+	return func(o *options) {
+		o.fixed("SetCappedMaxDocs")
+		o.cappedMaxDocs = n
+	}
+}
+
+// setupTimeout 第一次写入前初始化集合的最长时间
+const setupTimeout = 10 * time.Second
+
+// setup 在钩子创建时解析写入参数，错误输出到out。集合在第一次写入时初始化
+func (e *defaultExec) setup(o *options) {
+	wc, err := parseWriteConcern(o.writeConcern)
+	if err != nil {
+		o.warn("Write concern error: %s", err.Error())
+	}
+	e.wc = wc
+	e.setupPolicies(o)
+}
+
+// ensureCollection 在第一次写入时初始化集合，使用基础上下文且最长等待setupTimeout，
+// 失败时输出错误且不再重试
+func (e *defaultExec) ensureCollection() {
+	e.setupOnce.Do(func() {
+		o := e.options()
+		ctx, cancel := context.WithTimeout(o.ctx, setupTimeout)
+		defer cancel()
+		e.setupCollection(ctx, o)
+	})
+}
+
+// setupCollection 按参数创建时序集合、固定集合或TTL索引
+func (e *defaultExec) setupCollection(ctx context.Context, o *options) {
+	if o.timeSeries != nil {
+		if err := e.ensureTimeSeries(ctx, o); err != nil {
+			o.warn("Time-series collection error: %s", err.Error())
+		}
+		return
+	}
+	if o.cappedSize > 0 {
+		if err := e.ensureCapped(ctx, o); err != nil {
+			o.warn("Capped collection error: %s", err.Error())
+		}
+		if o.ttl > 0 {
+			o.warn("TTL index is not supported on capped collection %s", e.cName)
+		}
+		return
+	}
+	if o.ttl > 0 {
+		if err := e.ensureTTL(ctx, o); err != nil {
+			o.warn("TTL index error: %s", err.Error())
+		}
+	}
+}
+
+// collectionInfo 集合的创建参数
+type collectionInfo struct {
+	Options struct {
+		Capped bool  `bson:"capped"`
+		Size   int64 `bson:"size"`
+		Max    int64 `bson:"max"`
+	} `bson:"options"`
+}
+
+// lookupCollection 查询集合的创建参数，集合不存在时返回nil
+func (e *defaultExec) lookupCollection(ctx context.Context, db *mongo.Database) (*collectionInfo, error) {
+	var res struct {
+		Cursor struct {
+			FirstBatch []collectionInfo `bson:"firstBatch"`
+		} `bson:"cursor"`
+	}
+	err := db.RunCommand(ctx, bson.D{
+		{Key: "listCollections", Value: 1},
+		{Key: "filter", Value: bson.D{{Key: "name", Value: e.cName}}},
+	}).Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Cursor.FirstBatch) == 0 {
+		return nil, nil
+	}
+	return &res.Cursor.FirstBatch[0], nil
+}
+
+// ensureCapped 集合不存在时创建固定集合，已存在且参数不同时输出警告
+func (e *defaultExec) ensureCapped(ctx context.Context, o *options) error {
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+
+	info, err := e.lookupCollection(ctx, db)
+	if err != nil {
+		return err
+	}
+	if info != nil {
+		// 服务端会将大小向上取整为256的倍数
+		size := (o.cappedSize + 255) / 256 * 256
+		opts := info.Options
+		if !opts.Capped || (opts.Size != o.cappedSize && opts.Size != size) || opts.Max != o.cappedMaxDocs {
+			o.warn("Collection %s already exists with different capped settings", e.cName)
+		}
+		return nil
+	}
+
+	cmd := bson.D{
+		{Key: "create", Value: e.cName},
+		{Key: "capped", Value: true},
+		{Key: "size", Value: o.cappedSize},
+	}
+	if o.cappedMaxDocs > 0 {
+		cmd = append(cmd, bson.E{Key: "max", Value: o.cappedMaxDocs})
+	}
+	return db.RunCommand(ctx, cmd).Err()
+}
+
+// ttlSeconds 将过期时间向上取整为秒，服务端的过期时间以秒为单位
+func ttlSeconds(ttl time.Duration) int64 {
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// ensureTTL 在时间字段上建立TTL索引，索引已存在时不做处理
+func (e *defaultExec) ensureTTL(ctx context.Context, o *options) error {
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+	_, err = db.Collection(e.cName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: o.timeField, Value: 1}},
+		Options: mopts.Index().SetExpireAfterSeconds(int32(ttlSeconds(o.ttl))),
+	})
+	return err
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetupDeferredToFirstWrite(t *testing.T) {
+	coll := silentClient(t).Database("logger_test").Collection("logs")
+	for _, opt := range []Option{SetTTL(time.Hour), SetCapped(1 << 20), SetTimeSeries("time", "", "")} {
+		// 创建钩子时不访问数据库，服务端无应答时New也不阻塞
+		start := time.Now()
+		h := New(SetExec(NewExecWithCollection(coll)), SetAutoStart(false), opt)
+		if d := time.Since(start); d > time.Second {
+			t.Fatalf("New blocked for %s", d)
+		}
+		h.Close()
+	}
+}
+
+func TestTTLSeconds(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want int64
+	}{
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Hour, 3600},
+	}
+	for _, tt := range tests {
+		if got := ttlSeconds(tt.ttl); got != tt.want {
+			t.Errorf("ttlSeconds(%s) = %d, want %d", tt.ttl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// FlushOnSignal 收到信号时关闭钩子并写入队列中剩余的条目，未指定信号时监听SIGINT和SIGTERM。
+// 写入完成后停止监听并重新发送该信号，进程按信号的默认行为退出。
+// 重复调用时替换之前的监听，返回的函数用于取消监听
+func (h *Hook) FlushOnSignal(signals ...os.Signal) (cancel func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	h.signalLock.Lock()
+	defer h.signalLock.Unlock()
+	if h.signalStop != nil {
+		h.signalStop()
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, signals...)
+	go func() {
+		select {
+		case sig := <-ch:
+			h.Close()
+			signal.Stop(ch)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+	h.signalStop = stop
+
+	return func() {
+		h.signalLock.Lock()
+		defer h.signalLock.Unlock()
+		stop()
+	}
+}
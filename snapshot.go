@@ -0,0 +1,50 @@
+package logger
+
+import "github.com/sirupsen/logrus"
+
+// PendingSnapshot 返回缓冲区中尚未写入的条目(包括等待批量写入的条目)的副本，不会取出条目。
+// 快照只是某一时刻的近似结果，正在由工作线程处理及溢出文件中的条目不包含在内，
+// 副本与原条目共享字段的值，调用方不应修改字段中的引用类型
+func (h *Hook) PendingSnapshot() []*logrus.Entry {
+	entries := h.buf.snapshot()
+	if h.batch != nil {
+		entries = append(entries, h.batch.snapshot()...)
+	}
+	return entries
+}
+
+// snapshot 按取出的顺序返回缓冲区中条目的副本，持有锁时复制，避免与取出条目的工作线程竞争
+func (b *buffer) snapshot() []*logrus.Entry {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	entries := make([]*logrus.Entry, 0, b.size)
+	for _, lane := range []int{highLane, lowLane} {
+		r := &b.lanes[lane]
+		for i := 0; i < r.size; i++ {
+			entries = append(entries, cloneEntry(r.entries[(r.head+i)%len(r.entries)]))
+		}
+	}
+	return entries
+}
+
+// snapshot 返回等待批量写入的条目的副本
+func (b *batcher) snapshot() []*logrus.Entry {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	entries := make([]*logrus.Entry, len(b.entries))
+	for i, entry := range b.entries {
+		entries[i] = cloneEntry(entry)
+	}
+	return entries
+}
+
+// cloneEntry 复制条目及其字段，副本不从条目池中获取
+func cloneEntry(e *logrus.Entry) *logrus.Entry {
+	entry := *e
+	entry.Data = make(logrus.Fields, len(e.Data))
+	for k, v := range e.Data {
+		entry.Data[k] = v
+	}
+	return &entry
+}
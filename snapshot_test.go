@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPendingSnapshotConcurrent(t *testing.T) {
+	for _, batchSize := range []int{0, 4} {
+		exec := NewMemoryExec()
+		slow := funcExec(func(entry *logrus.Entry) error {
+			time.Sleep(time.Millisecond)
+			return exec.Exec(entry)
+		})
+		h := New(SetExec(slow), SetBatchSize(batchSize), SetPoolEntries(true),
+			SetExtra(map[string]interface{}{"app": "test"}))
+		for i := 0; i < 100; i++ {
+			entry := testEntry(logrus.InfoLevel, "a")
+			entry.Data["i"] = i
+			h.Fire(entry)
+		}
+
+		// 工作线程取出、修改并回收条目的同时读取快照
+		done := make(chan struct{})
+		go func() {
+			h.Drain()
+			close(done)
+		}()
+		for waiting := true; waiting; {
+			select {
+			case <-done:
+				waiting = false
+			default:
+				for _, entry := range h.PendingSnapshot() {
+					for range entry.Data {
+					}
+				}
+				runtime.Gosched()
+			}
+		}
+		h.Flush()
+		if n := len(exec.Entries()); n != 100 {
+			t.Fatalf("batch size %d: %d entries written, want 100", batchSize, n)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetSpillFile 设置溢出文件，队列已满时条目以长度前缀格式追加到文件，
+// 队列出现空位后按顺序重新入队；钩子创建时会重放文件中遗留的条目
+func SetSpillFile(path string) Option {
+	return func(o *options) {
+		o.fixed("SetSpillFile")
+		o.spillFile = path
+	}
+}
+
+// spill 基于文件的溢出缓冲区，每条记录为4字节大端长度与序列化后的条目
+type spill struct {
+	lock    sync.Mutex
+	file    *os.File
+	readOff int64
+	size    int64
+	pending int
+	written int
+	read    int
+
+	serialize   Serializer
+	deserialize Deserializer
+}
+
+// openSpill 打开溢出文件并统计遗留的条目，末尾不完整的记录将被截断
+func openSpill(path string, serialize Serializer, deserialize Deserializer) (*spill, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &spill{file: file, serialize: serialize, deserialize: deserialize}
+	for {
+		n, err := s.recordLen(s.size)
+		if err != nil {
+			break
+		}
+		s.size += 4 + n
+		s.pending++
+		s.written++
+	}
+	if err := file.Truncate(s.size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// recordLen 返回off处记录的负载长度，记录不完整时返回错误
+func (s *spill) recordLen(off int64) (int64, error) {
+	var header [4]byte
+	if _, err := s.file.ReadAt(header[:], off); err != nil {
+		return 0, err
+	}
+	n := int64(binary.BigEndian.Uint32(header[:]))
+	fi, err := s.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if off+4+n > fi.Size() {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+// write 将条目追加到溢出文件
+func (s *spill) write(entry *logrus.Entry) error {
+	buf, err := s.serialize(entry)
+	if err != nil {
+		return err
+	}
+	record := frame(buf)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.file.Write(record); err != nil {
+		return err
+	}
+	s.size += int64(len(record))
+	s.pending++
+	s.written++
+	return nil
+}
+
+// frame 为序列化后的条目加上4字节大端长度
+func frame(buf []byte) []byte {
+	record := make([]byte, 4+len(buf))
+	binary.BigEndian.PutUint32(record, uint32(len(buf)))
+	copy(record[4:], buf)
+	return record
+}
+
+func (s *spill) len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.pending
+}
+
+// progress 返回累计写入与取出的条目数
+func (s *spill) progress() (written, read int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.written, s.read
+}
+
+// replay 按顺序取出溢出的条目交给put，put返回false时停止，条目保留在文件中
+func (s *spill) replay(put func(*logrus.Entry) bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.pending == 0 {
+		return nil
+	}
+	for s.pending > 0 {
+		n, err := s.recordLen(s.readOff)
+		if err != nil {
+			return err
+		}
+		buf := make([]byte, n)
+		if _, err := s.file.ReadAt(buf, s.readOff+4); err != nil {
+			return err
+		}
+		entry, err := s.deserialize(buf)
+		if err == nil && !put(entry) {
+			return nil
+		}
+		s.readOff += 4 + n
+		s.pending--
+		s.read++
+		if err != nil {
+			return err
+		}
+	}
+
+	// 全部条目重放后清空文件
+	s.readOff, s.size = 0, 0
+	return s.file.Truncate(0)
+}
+
+func (s *spill) close() error {
+	return s.file.Close()
+}
+
+// enqueueSpill 缓冲区已满或溢出文件中仍有条目时将条目写入溢出文件，保证条目顺序
+func (h *Hook) enqueueSpill(entry *logrus.Entry) {
+	if h.spill.len() == 0 {
+		if _, added, _ := h.buf.put(entry, DropNewest); added {
+			atomic.AddUint64(&h.stats.enqueued, 1)
+			h.checkWaterMarks()
+			h.push(entry.Level)
+			return
+		}
+	}
+
+	if err := h.spill.write(entry); err != nil {
+		atomic.AddUint64(&h.stats.dropped, 1)
+		h.drop(entry, DropSpillError)
+		h.release(entry)
+		h.warn("Spill file error: %s", err.Error())
+		return
+	}
+	h.release(entry)
+	atomic.AddUint64(&h.stats.enqueued, 1)
+	h.replaySpill()
+}
+
+// replaySpill 将溢出文件中的条目放入缓冲区，直到缓冲区已满
+func (h *Hook) replaySpill() {
+	if h.spill == nil {
+		return
+	}
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if h.terminated {
+		return
+	}
+
+	err := h.spill.replay(func(entry *logrus.Entry) bool {
+		_, added, _ := h.buf.put(entry, DropNewest)
+		if added {
+			h.push(entry.Level)
+		}
+		return added
+	})
+	if err != nil {
+		h.warn("Spill file error: %s", err.Error())
+	}
+}
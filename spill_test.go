@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSpillKeepsOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spill")
+	exec := newGateExec()
+	h := New(SetExec(exec), SetMaxQueues(2), SetMaxWorkers(1), SetSpillFile(path))
+	h.Fire(testEntry(logrus.InfoLevel, "0"))
+	<-exec.started
+	for i := 1; i < 8; i++ {
+		h.Fire(testEntry(logrus.InfoLevel, strconv.Itoa(i)))
+	}
+	if n := h.Stats().Spilled; n != 5 {
+		t.Fatalf("spilled = %d, want 5", n)
+	}
+
+	close(exec.gate)
+	h.Drain()
+	h.Close()
+	assertMessages(t, exec.Entries(), "0", "1", "2", "3", "4", "5", "6", "7")
+	if h.Stats().Dropped != 0 {
+		t.Fatalf("dropped = %d", h.Stats().Dropped)
+	}
+}
+
+func TestSpillCrashReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spill")
+	exec := newGateExec()
+	crashed := New(SetExec(exec), SetMaxQueues(2), SetMaxWorkers(1), SetSpillFile(path), SetOut(ioutil.Discard))
+	crashed.Fire(testEntry(logrus.InfoLevel, "0"))
+	<-exec.started
+	for i := 1; i < 8; i++ {
+		crashed.Fire(testEntry(logrus.InfoLevel, strconv.Itoa(i)))
+	}
+
+	// 进程退出时溢出文件中的条目尚未写入，文件末尾还有一条不完整的记录
+	crashed.spill.close()
+	defer func() {
+		close(exec.gate)
+		crashed.Flush()
+	}()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte{0, 0, 1})
+	file.Close()
+
+	mem := NewMemoryExec()
+	h := New(SetExec(mem), SetSpillFile(path))
+	h.Close()
+	assertMessages(t, mem.Entries(), "3", "4", "5", "6", "7")
+
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Fatalf("spill file was not emptied after replay: %v, %v", fi, err)
+	}
+}
+
+func TestReplayKeepsDocumentID(t *testing.T) {
+	entry := testEntry(logrus.ErrorLevel, "failed")
+	entry.Data["user"] = "alice"
+	tests := []struct {
+		serialize   Serializer
+		deserialize Deserializer
+	}{
+		{marshalEntry, unmarshalEntry},
+		{MsgpackSerializer, MsgpackDeserializer},
+	}
+	opts := defaultOptions
+	opts.idGenerator = HashID
+	e := &defaultExec{cfg: func() *options { return &opts }}
+	want := e.document(entry).(bson.M)["_id"]
+	for i, tt := range tests {
+		buf, err := tt.serialize(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replayed, err := tt.deserialize(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 重放的条目与原条目的 _id 相同，按 _id 替换写入时不会产生重复的文档
+		if id := e.document(replayed).(bson.M)["_id"]; id != want {
+			t.Fatalf("case %d: _id = %v, want %v", i, id, want)
+		}
+	}
+}
+
+func TestReplayFileSkipsBadRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var lines, records []byte
+	for _, msg := range []string{"a", "b"} {
+		buf, err := marshalEntry(testEntry(logrus.InfoLevel, msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(append(lines, buf...), '\n')
+		if msg == "a" {
+			// 无法解析的JSON行
+			lines = append(lines, "{\"level\": \n"...)
+		}
+		header := make([]byte, 4)
+		binary.BigEndian.PutUint32(header, uint32(len(buf)))
+		records = append(append(records, header...), buf...)
+	}
+	// 末尾不完整的记录：长度前缀声明的数据没有写完
+	truncated := make([]byte, 4)
+	binary.BigEndian.PutUint32(truncated, 64)
+	records = append(append(records, truncated...), `{"le`...)
+
+	for name, data := range map[string][]byte{"lines": lines, "records": records} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		exec := NewMemoryExec()
+		written, failed, err := ReplayFile(path, exec)
+		if err != nil || written != 2 || failed != 1 {
+			t.Fatalf("%s: written = %d, failed = %d, err = %v, want 2, 1, nil", name, written, failed, err)
+		}
+		assertMessages(t, exec.Entries(), "a", "b")
+	}
+}
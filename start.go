@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrNotStarted 钩子尚未启动且队列已满
+var ErrNotStarted = errors.New("mongo hook is not started")
+
+// SetAutoStart 设置New是否立即启动工作线程，默认为true。
+// 设置为false时需在数据库客户端就绪后调用Start，启动前的条目缓存在队列中，
+// 队列已满时按溢出策略处理，阻塞策略(Block)下Fire不等待而是返回ErrNotStarted
+func SetAutoStart(autoStart bool) Option {
+	return func(o *options) {
+		o.fixed("SetAutoStart")
+		o.autoStart = autoStart
+	}
+}
+
+// Start 启动工作线程，写入启动前缓存的条目，重复调用是安全的。
+// Flush与Drain会先启动工作线程
+func (h *Hook) Start() {
+	h.startOnce.Do(func() {
+		h.q.Run()
+		atomic.StoreInt32(&h.started, 1)
+	})
+}
+
+// isStarted 判断工作线程是否已启动
+func (h *Hook) isStarted() bool {
+	return atomic.LoadInt32(&h.started) == 1
+}
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats 钩子的运行统计
+type Stats struct {
+	QueueLength     int           // 队列中等待处理的条目数
+	QueueCapacity   int           // 队列容量(maxQueues)
+	PendingJobs     int           // 任务队列中等待执行的任务数
+	Spilled         int           // 溢出文件中等待重放的条目数
+	Workers         int           // 当前的工作线程数
+	ActiveWorkers   int           // 正在处理条目的工作线程数
+	Enqueued        uint64        // 累计入队的条目数
+	Dropped         uint64        // 累计丢弃的条目数
+	Sampled         uint64        // 累计被采样丢弃的条目数
+	Deduped         uint64        // 累计被去重合并的条目数
+	Written         uint64        // 累计写入成功的条目数
+	Failed          uint64        // 累计重试后仍写入失败的条目数
+	Execs           uint64        // 累计调用Exec的次数
+	ExecErrors      uint64        // 累计调用Exec失败的次数
+	ExecDuration    time.Duration // 累计调用Exec的耗时
+	Breaker         BreakerState  // 熔断器状态
+	ErrorsDropped   uint64        // 累计因错误通道已满而丢弃的错误数
+	BlockedDuration time.Duration // 累计因队列已满阻塞Fire的时间(阻塞策略)，与丢弃计数分别统计
+}
+
+// counters 使用原子操作维护的计数器(需保持64位对齐)
+type counters struct {
+	enqueued      uint64
+	dropped       uint64
+	sampled       uint64
+	deduped       uint64
+	written       uint64
+	failed        uint64
+	execs         uint64
+	errors        uint64
+	nanos         uint64
+	errorsDropped uint64
+	active        int64
+}
+
+// Stats 返回钩子当前的运行统计
+func (h *Hook) Stats() Stats {
+	return Stats{
+		QueueLength:     h.buf.len(),
+		QueueCapacity:   h.options().maxQueues,
+		PendingJobs:     h.q.Len(),
+		Spilled:         h.spilled(),
+		Workers:         h.workers(),
+		ActiveWorkers:   int(atomic.LoadInt64(&h.stats.active)),
+		Enqueued:        atomic.LoadUint64(&h.stats.enqueued),
+		Dropped:         atomic.LoadUint64(&h.stats.dropped),
+		Sampled:         atomic.LoadUint64(&h.stats.sampled),
+		Deduped:         atomic.LoadUint64(&h.stats.deduped),
+		Written:         atomic.LoadUint64(&h.stats.written),
+		Failed:          atomic.LoadUint64(&h.stats.failed),
+		Execs:           atomic.LoadUint64(&h.stats.execs),
+		ExecErrors:      atomic.LoadUint64(&h.stats.errors),
+		ExecDuration:    time.Duration(atomic.LoadUint64(&h.stats.nanos)),
+		Breaker:         h.breakerState(),
+		ErrorsDropped:   atomic.LoadUint64(&h.stats.errorsDropped),
+		BlockedDuration: h.buf.blockedFor(),
+	}
+}
+
+func (h *Hook) workers() int {
+	if c, ok := h.q.(workerCounter); ok {
+		return c.Workers()
+	}
+	return h.options().maxWorkers
+}
+
+func (h *Hook) spilled() int {
+	if h.spill == nil {
+		return 0
+	}
+	return h.spill.len()
+}
+
+func (h *Hook) breakerState() BreakerState {
+	if h.breaker == nil {
+		return BreakerClosed
+	}
+	return h.breaker.current()
+}
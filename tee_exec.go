@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TeeDiffHandle 两个Exec的写入结果，任意一个写入失败时调用
+type TeeDiffHandle func(primaryErr, secondaryErr error)
+
+type teeExec struct {
+	primary   ExecCloser
+	secondary ExecCloser
+	diff      TeeDiffHandle
+}
+
+// TeeExec 将条目同时写入primary与secondary，用于迁移时比较新旧存储。
+// 钩子只使用primary的结果，任意一个写入失败时调用diff
+func TeeExec(primary, secondary ExecCloser, diff TeeDiffHandle) ExecCloser {
+	return &teeExec{primary: primary, secondary: secondary, diff: diff}
+}
+
+func (t *teeExec) bind(cfg func() *options) {
+	for _, exec := range []ExecCloser{t.primary, t.secondary} {
+		if b, ok := exec.(optionsBinder); ok {
+			b.bind(cfg)
+		}
+	}
+}
+
+func (t *teeExec) cancellable(batch bool) bool {
+	return cancellable(t.primary, batch) && cancellable(t.secondary, batch)
+}
+
+// compare 有写入失败时调用diff，返回primary的结果
+func (t *teeExec) compare(primaryErr, secondaryErr error) error {
+	if (primaryErr != nil || secondaryErr != nil) && t.diff != nil {
+		t.diff(primaryErr, secondaryErr)
+	}
+	return primaryErr
+}
+
+func (t *teeExec) Exec(entry *logrus.Entry) error {
+	return t.compare(t.primary.Exec(entry), t.secondary.Exec(entry))
+}
+
+func (t *teeExec) ExecContext(ctx context.Context, entry *logrus.Entry) error {
+	return t.compare(execContext(ctx, t.primary, entry), execContext(ctx, t.secondary, entry))
+}
+
+func (t *teeExec) BatchExec(entries []*logrus.Entry) error {
+	return t.compare(batchExec(t.primary, entries), batchExec(t.secondary, entries))
+}
+
+func (t *teeExec) BatchExecContext(ctx context.Context, entries []*logrus.Entry) error {
+	return t.compare(batchExecContext(ctx, t.primary, entries), batchExecContext(ctx, t.secondary, entries))
+}
+
+func (t *teeExec) Ping(ctx context.Context) error {
+	return t.primary.Ping(ctx)
+}
+
+func (t *teeExec) Close() error {
+	var errs MultiError
+	for _, exec := range []ExecCloser{t.primary, t.secondary} {
+		if err := exec.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.errorOrNil()
+}
+
+// execContext 使用上下文写入条目，Exec未实现ContextExecer时直接写入
+func execContext(ctx context.Context, exec ExecCloser, entry *logrus.Entry) error {
+	if ce, ok := exec.(ContextExecer); ok {
+		return ce.ExecContext(ctx, entry)
+	}
+	return exec.Exec(entry)
+}
+
+// batchExec 批量写入条目，Exec未实现BatchExecer时逐条写入
+func batchExec(exec ExecCloser, entries []*logrus.Entry) error {
+	if be, ok := exec.(BatchExecer); ok {
+		return be.BatchExec(entries)
+	}
+	return eachEntry(entries, exec.Exec)
+}
+
+// batchExecContext 使用上下文批量写入条目，Exec未实现BatchContextExecer时按batchExec写入
+func batchExecContext(ctx context.Context, exec ExecCloser, entries []*logrus.Entry) error {
+	if be, ok := exec.(BatchContextExecer); ok {
+		return be.BatchExecContext(ctx, entries)
+	}
+	if _, ok := exec.(BatchExecer); ok {
+		return batchExec(exec, entries)
+	}
+	return eachEntry(entries, func(entry *logrus.Entry) error {
+		return execContext(ctx, exec, entry)
+	})
+}
+
+// eachEntry 逐条写入条目，部分失败时返回BatchError，全部失败时返回第一个错误
+func eachEntry(entries []*logrus.Entry, write func(*logrus.Entry) error) error {
+	var failed []int
+	var firstErr error
+	for i, entry := range entries {
+		if err := write(entry); err != nil {
+			failed = append(failed, i)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	if len(failed) == len(entries) {
+		return firstErr
+	}
+	return &BatchError{Indices: failed, Err: firstErr}
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"time"
+)
+
+// SetExecTimeout 设置单次写入的超时时间，超时的条目进入重试和死信流程。
+// 超时只对能被上下文中止的写入生效：Exec需实现ContextExecer(批量写入时为BatchContextExecer)，
+// 工作线程等待写入返回后再重试；其余Exec的写入不设超时
+func SetExecTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.execTimeout = d
+	}
+}
+
+// cancelReporter 能够报告写入是否可被上下文中止的Exec，用于包装其他Exec或部分写入路径不支持上下文的Exec
+type cancelReporter interface {
+	cancellable(batch bool) bool
+}
+
+// cancellable 报告exec的单条(batch为true时为批量)带上下文写入能否被上下文中止
+func cancellable(exec ExecCloser, batch bool) bool {
+	if r, ok := exec.(cancelReporter); ok {
+		return r.cancellable(batch)
+	}
+	if batch {
+		if _, ok := exec.(BatchContextExecer); ok {
+			return true
+		}
+		if _, ok := exec.(BatchExecer); ok {
+			return false
+		}
+	}
+	_, ok := exec.(ContextExecer)
+	return ok
+}
+
+// withTimeout 使用带超时的上下文执行写入，未设置超时或fn不会在上下文取消时返回时直接执行
+func (h *Hook) withTimeout(ctx context.Context, cancellable bool, fn func(ctx context.Context) error) error {
+	d := h.options().execTimeout
+	if d <= 0 || !cancellable {
+		return fn(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	return fn(ctx)
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pm-esd/mongodb"
+	"github.com/sirupsen/logrus"
+)
+
+func TestExecTimeoutSkipsNonContextExec(t *testing.T) {
+	var calls int32
+	slow := funcExec(func(entry *logrus.Entry) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	})
+	h := New(SetExec(slow), SetExecTimeout(5*time.Millisecond), SetRetry(3, time.Millisecond),
+		SetErrorHandler(func(entry *logrus.Entry, err error) { t.Errorf("error = %v", err) }))
+	h.Fire(testEntry(logrus.InfoLevel, "slow"))
+	h.Flush()
+
+	// 不能被上下文中止的写入不设超时，条目只写入一次
+	if calls := atomic.LoadInt32(&calls); calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
+
+func TestCancellable(t *testing.T) {
+	ctx := funcCtxExec(func(context.Context, *logrus.Entry) error { return nil })
+	plain := funcExec(func(*logrus.Entry) error { return nil })
+	client := NewExec(new(mongodb.MongoDBClient), "logs")
+	tests := []struct {
+		exec ExecCloser
+		want bool
+	}{
+		{ctx, true},
+		{plain, false},
+		{client, false},
+		{TeeExec(ctx, ctx, nil), true},
+		{TeeExec(ctx, plain, nil), false},
+		{MultiExec(ctx, client), false},
+		{sharedExec{ctx}, true},
+	}
+	for i, tt := range tests {
+		if got := cancellable(tt.exec, false); got != tt.want {
+			t.Errorf("%d: cancellable = %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestExecTimeoutContextExec(t *testing.T) {
+	var active, overlaps, calls int32
+	// 写入在上下文取消后稍晚才返回
+	exec := funcCtxExec(func(ctx context.Context, entry *logrus.Entry) error {
+		if atomic.AddInt32(&active, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		defer atomic.AddInt32(&active, -1)
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		return ctx.Err()
+	})
+	h := New(SetExec(exec), SetMaxWorkers(1), SetExecTimeout(10*time.Millisecond), SetRetry(3, time.Millisecond),
+		SetErrorHandler(func(entry *logrus.Entry, err error) {
+			if err != context.DeadlineExceeded {
+				t.Errorf("error = %v, want context.DeadlineExceeded", err)
+			}
+		}))
+	h.Fire(testEntry(logrus.InfoLevel, "a"))
+	h.Flush()
+
+	// 工作线程等待超时的写入返回后才重试，不会有两次写入同时进行
+	if calls, overlaps := atomic.LoadInt32(&calls), atomic.LoadInt32(&overlaps); calls != 3 || overlaps != 0 {
+		t.Fatalf("calls = %d, overlapping calls = %d", calls, overlaps)
+	}
+}
+
+// funcCtxExec 由函数实现的ContextExecer
+type funcCtxExec func(context.Context, *logrus.Entry) error
+
+func (f funcCtxExec) Exec(entry *logrus.Entry) error { return f(context.Background(), entry) }
+func (f funcCtxExec) ExecContext(ctx context.Context, entry *logrus.Entry) error {
+	return f(ctx, entry)
+}
+func (f funcCtxExec) Ping(ctx context.Context) error { return nil }
+func (f funcCtxExec) Close() error                   { return nil }
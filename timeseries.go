@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// errTimeSeriesUnsupported 服务端版本低于5.0，不支持时序集合
+var errTimeSeriesUnsupported = errors.New("time-series collections require MongoDB 5.0 or later")
+
+// SetTimeSeries 设置默认Exec在集合不存在时创建时序集合(MongoDB 5.0+)，timeField 同时作为默认文档的时间字段，
+// metaField 不为空时默认文档的 level 与 hostname 字段移入该子文档，granularity 可以是 seconds、minutes、hours 或空字符串。
+// 设置了SetTTL时以 expireAfterSeconds 过期；与SetCapped同时设置时只创建时序集合，服务端不支持时输出错误
+func SetTimeSeries(timeField, metaField string, granularity string) Option {
+	return func(o *options) {
+		o.fixed("SetTimeSeries")
+		if timeField == "" {
+			return
+		}
+		o.timeField = timeField
+		o.timeSeries = &timeSeries{
+			timeField:   timeField,
+			metaField:   metaField,
+			granularity: granularity,
+		}
+	}
+}
+
+// timeSeries 时序集合的参数
+type timeSeries struct {
+	timeField   string
+	metaField   string
+	granularity string
+}
+
+// ensureTimeSeries 集合不存在时创建时序集合，已存在且不是时序集合时输出警告
+func (e *defaultExec) ensureTimeSeries(ctx context.Context, o *options) error {
+	db, err := e.database()
+	if err != nil {
+		return err
+	}
+
+	var info struct {
+		VersionArray []int32 `bson:"versionArray"`
+	}
+	if err := db.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info); err != nil {
+		return err
+	}
+	if len(info.VersionArray) == 0 || info.VersionArray[0] < 5 {
+		return errTimeSeriesUnsupported
+	}
+
+	var res struct {
+		Cursor struct {
+			FirstBatch []struct {
+				Type string `bson:"type"`
+			} `bson:"firstBatch"`
+		} `bson:"cursor"`
+	}
+	err = db.RunCommand(ctx, bson.D{
+		{Key: "listCollections", Value: 1},
+		{Key: "filter", Value: bson.D{{Key: "name", Value: e.cName}}},
+	}).Decode(&res)
+	if err != nil {
+		return err
+	}
+	if batch := res.Cursor.FirstBatch; len(batch) > 0 {
+		if batch[0].Type != "timeseries" {
+			o.warn("Collection %s already exists and is not a time-series collection", e.cName)
+		}
+		return nil
+	}
+
+	ts := o.timeSeries
+	spec := bson.D{{Key: "timeField", Value: ts.timeField}}
+	if ts.metaField != "" {
+		spec = append(spec, bson.E{Key: "metaField", Value: ts.metaField})
+	}
+	if ts.granularity != "" {
+		spec = append(spec, bson.E{Key: "granularity", Value: ts.granularity})
+	}
+	cmd := bson.D{
+		{Key: "create", Value: e.cName},
+		{Key: "timeseries", Value: spec},
+	}
+	if o.ttl > 0 {
+		cmd = append(cmd, bson.E{Key: "expireAfterSeconds", Value: ttlSeconds(o.ttl)})
+	}
+	return db.RunCommand(ctx, cmd).Err()
+}
+
+// timeSeriesMeta 将标识序列的字段移入时序集合的 metaField 子文档
+func timeSeriesMeta(item bson.M, ts *timeSeries) {
+	if ts == nil || ts.metaField == "" {
+		return
+	}
+	if _, ok := item[ts.metaField]; ok {
+		return
+	}
+	meta := make(bson.M)
+	for _, k := range []string{"level", "hostname"} {
+		if v, ok := item[k]; ok {
+			meta[k] = v
+			delete(item, k)
+		}
+	}
+	if len(meta) > 0 {
+		item[ts.metaField] = meta
+	}
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TraceExtractor 从条目中提取链路追踪的 trace ID 与 span ID
+type TraceExtractor func(*logrus.Entry) (traceID, spanID string)
+
+// SetTraceExtractor 设置链路追踪提取器，提取的非空ID保存为 trace_id 与 span_id 字段
+func SetTraceExtractor(extractor TraceExtractor) Option {
+	return func(o *options) {
+		o.traceExtractor = extractor
+	}
+}
+
+// FieldTraceExtractor 返回从指定字段(如 traceID、spanID)中读取ID的提取器
+func FieldTraceExtractor(traceKey, spanKey string) TraceExtractor {
+	return func(entry *logrus.Entry) (string, string) {
+		return fieldString(entry.Data[traceKey]), fieldString(entry.Data[spanKey])
+	}
+}
+
+func fieldString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case fmt.Stringer:
+		return s.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// extractTrace 将提取的ID写入条目
+func (h *Hook) extractTrace(entry *logrus.Entry) {
+	extractor := h.options().traceExtractor
+	if extractor == nil {
+		return
+	}
+	traceID, spanID := extractor(entry)
+	if traceID != "" {
+		entry.Data["trace_id"] = traceID
+	}
+	if spanID != "" {
+		entry.Data["span_id"] = spanID
+	}
+}
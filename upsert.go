@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SetUpsert 设置默认Exec是否按 _id 替换写入(不存在时插入)，需配合SetIDGenerator使用，
+// 重放溢出文件或死信时不会写入重复的文档。没有 _id 的文档仍然直接插入
+func SetUpsert(upsert bool) Option {
+	return func(o *options) {
+		o.upsert = upsert
+	}
+}
+
+// SetAppendOnly 设置默认Exec是否只以插入方式写入，每个条目在变更流中都对应一个insert事件。
+// 与SetUpsert不兼容：同时设置时NewStrict返回ErrAppendOnlyUpsert，New输出警告并关闭upsert；
+// ReplayFile在只追加模式下同样只插入，重放可能写入重复的文档
+func SetAppendOnly(appendOnly bool) Option {
+	return func(o *options) {
+		o.appendOnly = appendOnly
+	}
+}
+
+// upserts 判断是否按 _id 替换写入
+func (o *options) upserts() bool {
+	return o.upsert && !o.appendOnly
+}
+
+// save 写入单个文档
+func (e *defaultExec) save(ctx context.Context, t target, doc interface{}) error {
+	if e.options().upserts() {
+		if id, ok := documentID(doc); ok {
+			return e.upsertOne(ctx, t, id, doc)
+		}
+	}
+	return e.insertOne(ctx, t, doc)
+}
+
+// saveMany 写入多个文档
+func (e *defaultExec) saveMany(ctx context.Context, t target, docs []interface{}) error {
+	if e.options().upserts() {
+		return e.upsertMany(ctx, t, docs)
+	}
+	return e.insertMany(ctx, t, docs)
+}
+
+// documentID 返回文档的 _id
+func documentID(doc interface{}) (interface{}, bool) {
+	var id interface{}
+	switch d := doc.(type) {
+	case bson.M:
+		id = d["_id"]
+	case map[string]interface{}:
+		id = d["_id"]
+	}
+	return id, id != nil
+}
+
+func (e *defaultExec) upsertOne(ctx context.Context, t target, id, doc interface{}) error {
+	coll, err := e.driverCollection(t)
+	if err != nil {
+		return err
+	}
+	_, err = coll.ReplaceOne(ctx, bson.M{"_id": id}, doc, mopts.Replace().SetUpsert(true))
+	return unacknowledged(err)
+}
+
+// upsertMany 批量无序替换写入，没有 _id 的文档直接插入，部分文档写入失败时返回BatchError
+func (e *defaultExec) upsertMany(ctx context.Context, t target, docs []interface{}) error {
+	coll, err := e.driverCollection(t)
+	if err != nil {
+		return err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(docs))
+	for _, doc := range docs {
+		if id, ok := documentID(doc); ok {
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": id}).
+				SetReplacement(doc).
+				SetUpsert(true))
+			continue
+		}
+		models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
+	}
+
+	_, err = coll.BulkWrite(ctx, models, mopts.BulkWrite().SetOrdered(false))
+	return partialError(unacknowledged(err), len(docs), false)
+}
@@ -0,0 +1,48 @@
+package logger
+
+// validate 检查参数是否有效，返回第一个无效参数对应的错误
+func (o *options) validate() error {
+	if o.maxQueues <= 0 {
+		return ErrInvalidQueueSize
+	}
+	if o.queue == nil && o.maxWorkers <= 0 {
+		return ErrInvalidWorkers
+	}
+	if o.maxScaleWorkers != 0 && (o.minWorkers < 0 || o.maxScaleWorkers < 0 || o.minWorkers > o.maxScaleWorkers) {
+		return ErrInvalidWorkers
+	}
+	if !validWaterMark(o.highWater) || !validWaterMark(o.lowWater) {
+		return ErrInvalidWaterMark
+	}
+	if o.highWater.handle != nil && o.lowWater.handle != nil && o.lowWater.fraction > o.highWater.fraction {
+		return ErrInvalidWaterMark
+	}
+	if o.appendOnly && o.upsert {
+		return ErrAppendOnlyUpsert
+	}
+	if o.stackDepth < 0 {
+		return ErrInvalidStackDepth
+	}
+	return nil
+}
+
+func validWaterMark(w waterMark) bool {
+	return w.handle == nil || (w.fraction > 0 && w.fraction <= 1)
+}
+
+// reset 将err对应的无效参数恢复为默认值
+func (o *options) reset(err error) {
+	switch err {
+	case ErrInvalidQueueSize:
+		o.maxQueues = defaultOptions.maxQueues
+	case ErrInvalidWorkers:
+		o.maxWorkers = defaultOptions.maxWorkers
+		o.minWorkers, o.maxScaleWorkers = 0, 0
+	case ErrInvalidWaterMark:
+		o.highWater, o.lowWater = waterMark{}, waterMark{}
+	case ErrAppendOnlyUpsert:
+		o.upsert = false
+	case ErrInvalidStackDepth:
+		o.stackDepth = defaultOptions.stackDepth
+	}
+}
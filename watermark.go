@@ -0,0 +1,53 @@
+package logger
+
+import "sync/atomic"
+
+// WaterMarkHandle 队列深度越过水位时的回调
+type WaterMarkHandle func(depth, capacity int)
+
+type waterMark struct {
+	fraction float64
+	handle   WaterMarkHandle
+}
+
+// SetHighWaterMark 设置高水位，队列深度向上越过 capacity*fraction 时调用handle
+func SetHighWaterMark(fraction float64, handle WaterMarkHandle) Option {
+	return func(o *options) {
+		o.highWater = waterMark{fraction: fraction, handle: handle}
+	}
+}
+
+// SetLowWaterMark 设置低水位，越过高水位后队列深度回落到 capacity*fraction 时调用handle，
+// 未设置时以高水位作为回落的界限
+func SetLowWaterMark(fraction float64, handle WaterMarkHandle) Option {
+	return func(o *options) {
+		o.lowWater = waterMark{fraction: fraction, handle: handle}
+	}
+}
+
+// checkWaterMarks 根据队列深度检查水位，回调只在状态变化时调用
+func (h *Hook) checkWaterMarks() {
+	o := h.options()
+	high, low := o.highWater, o.lowWater
+	if high.handle == nil {
+		return
+	}
+
+	capacity := o.maxQueues
+	depth := h.buf.len()
+	highDepth := int(high.fraction * float64(capacity))
+	lowDepth := highDepth
+	if low.handle != nil {
+		lowDepth = int(low.fraction * float64(capacity))
+	}
+
+	if depth >= highDepth {
+		if atomic.CompareAndSwapInt32(&h.aboveHigh, 0, 1) {
+			high.handle(depth, capacity)
+		}
+	} else if depth <= lowDepth {
+		if atomic.CompareAndSwapInt32(&h.aboveHigh, 1, 0) && low.handle != nil {
+			low.handle(depth, capacity)
+		}
+	}
+}
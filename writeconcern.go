@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// SetWriteConcern 设置默认Exec写入时的WriteConcern，可以是 *writeconcern.WriteConcern、
+// int(w:N，0表示不确认写入)或string("majority"或标签名)。
+// 不确认写入(w:0)不等待服务端响应，以持久性换取吞吐量，写入失败时不会进入重试和死信
+func SetWriteConcern(w interface{}) Option {
+	return func(o *options) {
+		o.fixed("SetWriteConcern")
+		o.writeConcern = w
+	}
+}
+
+// parseWriteConcern 将设置的值转换为WriteConcern
+func parseWriteConcern(w interface{}) (*writeconcern.WriteConcern, error) {
+	switch v := w.(type) {
+	case nil:
+		return nil, nil
+	case *writeconcern.WriteConcern:
+		return v, nil
+	case int:
+		return writeconcern.New(writeconcern.W(v)), nil
+	case string:
+		if v == "majority" {
+			return writeconcern.New(writeconcern.WMajority()), nil
+		}
+		return writeconcern.New(writeconcern.WTagSet(v)), nil
+	}
+	return nil, errors.New("unsupported write concern type")
+}
+
+// insertOne 写入单个文档，有驱动层集合时通过驱动层集合写入
+func (e *defaultExec) insertOne(ctx context.Context, t target, doc interface{}) error {
+	coll, err := e.collection(ctx, t)
+	if err != nil {
+		return err
+	}
+	if coll == nil {
+		_, err = e.clientCollection(t.name).InsertOne(doc)
+		return err
+	}
+	_, err = coll.InsertOne(ctx, doc)
+	return unacknowledged(err)
+}
+
+// insertMany 写入多个文档，有驱动层集合时通过驱动层集合无序写入，单个文档失败不影响其余文档。
+// 部分文档写入失败时返回BatchError
+func (e *defaultExec) insertMany(ctx context.Context, t target, docs []interface{}) error {
+	coll, err := e.collection(ctx, t)
+	if err != nil {
+		return err
+	}
+	if coll == nil {
+		_, err = e.clientCollection(t.name).InsertMany(docs)
+		return partialError(err, len(docs), true)
+	}
+	_, err = coll.InsertMany(ctx, docs, mopts.InsertMany().SetOrdered(false))
+	return partialError(unacknowledged(err), len(docs), false)
+}
+
+// unacknowledged 不确认写入时驱动返回ErrUnacknowledgedWrite，视为写入成功
+func unacknowledged(err error) error {
+	if err == mongo.ErrUnacknowledgedWrite {
+		return nil
+	}
+	return err
+}